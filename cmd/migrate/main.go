@@ -1,20 +1,161 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"simple-goroutine-product/internal/config"
 	"simple-goroutine-product/internal/database"
-
-	"github.com/joho/godotenv"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
+const usage = `Usage: migrate [flags] <command> [arg]
+
+Commands:
+  up            apply every pending migration (default)
+  down N        revert the N most recent migrations
+  status        list migrations and whether they are applied
+  goto V        apply or revert migrations until V is the latest applied
+  create NAME   write empty up and down files for a new migration
+  force V       record migrations up to V as applied without running them
+
+Flags:
+`
+
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	dir := flag.String("dir", "internal/database/migrations", "migrations directory that create writes to")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
 	}
+	flag.Parse()
+
+	command, args := "up", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	// create only writes files and needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			fail("create needs a migration name")
+		}
+		paths, err := database.CreateMigration(*dir, args[0])
+		if err != nil {
+			log.Fatal("Failed to create migration: ", err)
+		}
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return
+	}
+
+	// Check the arguments before connecting
+	var n uint
+	switch command {
+	case "up", "status":
+		if len(args) != 0 {
+			fail(command + " takes no arguments")
+		}
+	case "down", "goto", "force":
+		n = number(args)
+	default:
+		fail(fmt.Sprintf("unknown command %q", command))
+	}
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Database.Driver == "memory" {
+		log.Fatal("DB_DRIVER is memory, there is no database to migrate")
+	}
+
+	// Interrupts abort waiting for the database or the migration lock
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Connect to database
-	database.ConnectDatabase()
+	db, err := database.ConnectDatabase(ctx, cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.CloseDatabase(db)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx, int(n))
+	case "goto":
+		err = migrator.Goto(ctx, n)
+	case "force":
+		err = migrator.Force(ctx, n)
+	case "status":
+		err = printStatus(ctx, migrator)
+	}
+	if err != nil {
+		// Deferred calls do not run after log.Fatal
+		database.CloseDatabase(db)
+		log.Fatalf("Failed to %s: %v", command, err)
+	}
+
+	if command != "status" {
+		log.Println("Database migration completed successfully")
+	}
+}
+
+// number parses the single numeric argument of a command
+func number(args []string) uint {
+	if len(args) != 1 {
+		fail("expected a single number argument")
+	}
+	n, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil {
+		fail(fmt.Sprintf("invalid number %q", args[0]))
+	}
+	return uint(n)
+}
+
+// printStatus lists every migration and when it was applied
+func printStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Unknown {
+			state = "applied, no file"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
 
-	log.Println("Database migration completed successfully")
+// fail reports a usage error and exits
+func fail(message string) {
+	fmt.Fprintf(os.Stderr, "migrate: %s\n\n", message)
+	flag.Usage()
+	os.Exit(2)
 }
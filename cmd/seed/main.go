@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"simple-goroutine-product/internal/config"
+	"simple-goroutine-product/internal/database"
+	"simple-goroutine-product/internal/fixtures"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"syscall"
+)
+
+const usage = `Usage: seed [flags] [FILE...]
+
+Loads products from YAML or JSON fixture files and generated fake products.
+Products are matched by name, so seeding twice changes nothing.
+
+Flags:
+`
+
+func main() {
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	generate := flag.Int("generate", 0, "number of fake products to generate")
+	seed := flag.Int64("seed", 1, "random seed for generated products")
+	truncate := flag.Bool("truncate", false, "permanently remove every product and reservation first")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 && *generate == 0 && !*truncate {
+		fail("nothing to seed, pass fixture files, -generate or -truncate")
+	}
+	if *generate < 0 {
+		fail("-generate cannot be negative")
+	}
+
+	// Read every fixture before connecting
+	var products []models.ProductRequest
+	for _, path := range flag.Args() {
+		fixture, err := fixtures.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		products = append(products, fixture.Products...)
+	}
+	products = append(products, fixtures.Generate(*generate, *seed)...)
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Database.Driver == "memory" {
+		log.Fatal("DB_DRIVER is memory, there is no database to seed")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to database
+	db, err := database.ConnectDatabase(ctx, cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.CloseDatabase(db)
+
+	if err := database.CheckSchema(ctx, db); err != nil {
+		database.CloseDatabase(db)
+		log.Fatal(err)
+	}
+
+	result, err := fixtures.Seed(ctx, repositories.NewProductRepository(db), products, *truncate)
+	if err != nil {
+		// Deferred calls do not run after log.Fatal
+		database.CloseDatabase(db)
+		log.Fatal("Failed to seed: ", err)
+	}
+
+	log.Printf("Seeded products: %d created, %d updated, %d unchanged", result.Created, result.Updated, result.Unchanged)
+}
+
+// fail reports a usage error and exits
+func fail(message string) {
+	fmt.Fprintf(os.Stderr, "seed: %s\n\n", message)
+	flag.Usage()
+	os.Exit(2)
+}
@@ -6,59 +6,231 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"simple-goroutine-product/internal/config"
 	"simple-goroutine-product/internal/database"
 	"simple-goroutine-product/internal/handlers"
+	"simple-goroutine-product/internal/health"
+	"simple-goroutine-product/internal/lifecycle"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/middlewares"
 	"simple-goroutine-product/internal/presenters"
 	"simple-goroutine-product/internal/repositories"
 	"simple-goroutine-product/internal/routes"
+	"simple-goroutine-product/internal/tracing"
 	"simple-goroutine-product/internal/validators"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"gorm.io/gorm"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Connect to database
-	database.ConnectDatabase()
+	// Interrupts also abort waiting for the database at startup
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Components are started in the order they are registered and
+	// stopped in reverse
+	app := lifecycle.New()
+
+	// Export traces; stopped last so spans from shutdown are flushed
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+	app.Append(lifecycle.Hook{
+		Name:   "tracing",
+		OnStop: shutdownTracing,
+	})
+
+	// Measure requests, presenter goroutines and queries
+	recorder := metrics.NewPrometheus()
+
+	// Open the product and reservation store
+	var db *gorm.DB
+	var productRepo repositories.ProductRepository
+	var reservationRepo repositories.ReservationRepository
+	if cfg.Database.Driver == "memory" {
+		log.Println("Using the in-memory store, data is lost on restart")
+		store := repositories.NewMemoryStore()
+		productRepo = repositories.NewMemoryProductRepository(store)
+		reservationRepo = repositories.NewMemoryReservationRepository(store)
+	} else {
+		db, err = database.ConnectDatabase(ctx, cfg.Database)
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.Append(lifecycle.Hook{
+			Name: "database",
+			OnStop: func(ctx context.Context) error {
+				return database.CloseDatabase(db)
+			},
+		})
+
+		if err := db.Use(metrics.NewGORMPlugin(recorder)); err != nil {
+			log.Fatal("Failed to register query metrics:", err)
+		}
+		if err := db.Use(tracing.NewGORMPlugin()); err != nil {
+			log.Fatal("Failed to register query tracing:", err)
+		}
 
-	// Initialize repositories
-	productRepo := repositories.NewProductRepository(database.GetDB())
+		// Migrations normally run through cmd/migrate before deploying
+		if cfg.Database.AutoMigrate {
+			if err := database.Migrate(ctx, db); err != nil {
+				log.Fatal("Failed to migrate database:", err)
+			}
+		}
+
+		productRepo = repositories.NewProductRepository(db)
+		reservationRepo = repositories.NewReservationRepository(db)
+	}
+	idempotencyRepo := idempotencyStore(cfg.Idempotency, db)
 
 	// Initialize presenters
-	productPresenter := presenters.NewProductPresenter(productRepo)
+	productPresenter := presenters.NewProductPresenter(productRepo, cfg.Presenters.OperationTimeout, recorder)
+	reservationPresenter := presenters.NewReservationPresenter(reservationRepo, cfg.Presenters.ReservationTTL, cfg.Presenters.OperationTimeout, recorder)
+	bulkPresenter := presenters.NewBulkPresenter(productRepo, cfg.Presenters.BulkWorkers, recorder)
+	importPresenter := presenters.NewImportPresenter(productRepo, recorder)
+	exportPresenter := presenters.NewExportPresenter(productRepo, recorder)
+
+	// Expire stale reservations in the background
+	app.Append(lifecycle.Background("reservation expiry", func(ctx context.Context) {
+		reservationPresenter.RunExpiry(ctx, time.Minute)
+	}))
+
+	// Empty the trash of products deleted longer ago than the retention
+	if cfg.Presenters.TrashRetention > 0 {
+		app.Append(lifecycle.Background("trash purge", func(ctx context.Context) {
+			productPresenter.RunPurge(ctx, time.Hour, cfg.Presenters.TrashRetention)
+		}))
+	}
+
+	// Forget expired idempotency keys in the background
+	app.Append(lifecycle.Background("idempotency expiry", func(ctx context.Context) {
+		middlewares.RunIdempotencyExpiry(ctx, idempotencyRepo, time.Hour)
+	}))
+
+	// Let writes started by requests finish before the database closes
+	app.Append(lifecycle.Hook{
+		Name: "presenters",
+		OnStop: func(ctx context.Context) error {
+			return errors.Join(productPresenter.Wait(ctx), reservationPresenter.Wait(ctx))
+		},
+	})
+
+	// Readiness checks, run concurrently on every /readyz request
+	checks := health.NewRegistry(cfg.Health.CheckTimeout)
+	if db != nil {
+		checks.Register("database", health.CheckerFunc(func(ctx context.Context) error {
+			return database.Ping(ctx, db)
+		}))
+		checks.Register("schema", health.CheckerFunc(func(ctx context.Context) error {
+			return database.CheckSchema(ctx, db)
+		}))
+	}
+	checks.Register("writes", health.Saturation(func() int {
+		return productPresenter.InFlight() + reservationPresenter.InFlight()
+	}, cfg.Health.MaxInFlightWrites))
 
 	// Initialize handlers
 	productHandler := handlers.NewProductHandler(productPresenter)
+	reservationHandler := handlers.NewReservationHandler(reservationPresenter)
+	bulkHandler := handlers.NewBulkHandler(bulkPresenter)
+	importHandler := handlers.NewImportHandler(importPresenter)
+	exportHandler := handlers.NewExportHandler(exportPresenter)
+	healthHandler := handlers.NewHealthHandler(checks)
 
 	// Initialize Echo
 	e := echo.New()
 
 	// Middleware
+	e.Use(middleware.RequestID())
+	e.Use(middlewares.Actor())
+	e.Use(middlewares.Tracing())
 	e.Use(middleware.Logger())
+	e.Use(middlewares.Metrics(recorder))
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.Server.CORSOrigins,
+	}))
 
 	// Custom validator
 	e.Validator = validators.NewValidator()
 
+	// Map domain errors to HTTP responses
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+
 	// Setup routes
-	routes.SetupRoutes(e, productHandler)
+	routes.SetupRoutes(e, productHandler, reservationHandler, bulkHandler, importHandler, exportHandler, healthHandler, recorder.Handler(), middlewares.Idempotency(idempotencyRepo, cfg.Idempotency.TTL))
 
-	// Get port from environment
-	port := os.Getenv("APP_PORT")
-	if port == "" {
-		port = "8080"
+	// Apply server timeouts
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+	addr := fmt.Sprintf(":%d", cfg.Server.Port)
+
+	// Stop accepting connections and drain in-flight requests first
+	serverErr := make(chan error, 1)
+	app.Append(lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			log.Printf("Server starting on port %d", cfg.Server.Port)
+			go func() {
+				if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serverErr <- err
+				}
+			}()
+			return nil
+		},
+		OnStop: e.Shutdown,
+	})
+
+	if err := app.Start(ctx); err != nil {
+		log.Fatal("Failed to start:", err)
 	}
 
-	// Start server
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(e.Start(":" + port))
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	case err := <-serverErr:
+		log.Println("Server failed:", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := app.Stop(shutdownCtx); err != nil {
+		log.Println("Shutdown incomplete:", err)
+		return
+	}
+	log.Println("Server stopped")
+}
+
+// idempotencyStore returns the store for Idempotency-Key responses. The
+// database is used unless the configured store is "memory" or there is no
+// database.
+func idempotencyStore(cfg config.IdempotencyConfig, db *gorm.DB) repositories.IdempotencyRepository {
+	if cfg.Store == "memory" || db == nil {
+		return repositories.NewMemoryIdempotencyRepository()
+	}
+	return repositories.NewIdempotencyRepository(db)
 }
@@ -0,0 +1,42 @@
+// Package apperrors defines the kinds of domain errors shared by the
+// repositories, presenters and validators. Handlers map each kind to an
+// HTTP status.
+package apperrors
+
+import "errors"
+
+// Domain error kinds. Every error returned on purpose wraps one of these so
+// callers can tell them apart with errors.Is without knowing where it came
+// from.
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write conflicts with the current state
+	// of a record
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is returned when input is rejected before it is written
+	ErrValidation = errors.New("validation failed")
+	// ErrTimeout is returned when an operation does not finish in time
+	ErrTimeout = errors.New("operation timeout")
+)
+
+// Error is a specific domain error of one of the error kinds
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// New creates a domain error of the given kind
+func New(kind error, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error kind
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
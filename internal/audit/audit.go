@@ -0,0 +1,33 @@
+// Package audit carries who made a change through the context, so the
+// repositories can record it in the audit trail next to the change itself.
+package audit
+
+import "context"
+
+// System is the actor recorded for changes made outside a request, such as
+// background jobs and the seed command
+const System = "system"
+
+// Actor identifies who made a change
+type Actor struct {
+	Name      string
+	RequestID string
+}
+
+// actorKey is the context key holding the Actor
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx. Without one the change is
+// attributed to System.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	if actor.Name == "" {
+		actor.Name = System
+	}
+	return actor
+}
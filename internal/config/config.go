@@ -0,0 +1,350 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the application configuration. Values are read from defaults,
+// then an optional YAML or TOML file, then the environment (including a
+// .env file), each overriding the one before.
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Presenters  PresenterConfig   `yaml:"presenters" toml:"presenters"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Health      HealthConfig      `yaml:"health" toml:"health"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port int `yaml:"port" toml:"port" env:"APP_PORT" validate:"min=1,max=65535"`
+	// ReadTimeout and WriteTimeout are off unless set. Product imports and
+	// exports are exempt from them so long streams are not cut off.
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT" validate:"min=0"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" validate:"min=0"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" validate:"min=0"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" validate:"gt=0"`
+	CORSOrigins     []string      `yaml:"cors_origins" toml:"cors_origins" env:"CORS_ALLOW_ORIGINS" validate:"min=1,dive,required"`
+}
+
+// DatabaseConfig configures the database connection and its pool
+type DatabaseConfig struct {
+	// Driver selects PostgreSQL, a SQLite file at Path, or the in-memory
+	// repositories, which need no database at all
+	Driver          string        `yaml:"driver" toml:"driver" env:"DB_DRIVER" validate:"oneof=postgres sqlite memory"`
+	Host            string        `yaml:"host" toml:"host" env:"DB_HOST" validate:"required_if=Driver postgres"`
+	Port            int           `yaml:"port" toml:"port" env:"DB_PORT" validate:"min=1,max=65535"`
+	User            string        `yaml:"user" toml:"user" env:"DB_USER" validate:"required_if=Driver postgres"`
+	Password        string        `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Name            string        `yaml:"name" toml:"name" env:"DB_NAME" validate:"required_if=Driver postgres"`
+	Path            string        `yaml:"path" toml:"path" env:"DB_PATH" validate:"required_if=Driver sqlite"`
+	SSLMode         string        `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSLMODE" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" validate:"min=1"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" validate:"min=0,ltefield=MaxOpenConns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" validate:"min=0"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" validate:"min=0"`
+	// ConnectTimeout bounds how long startup waits for the database
+	ConnectTimeout   time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" validate:"gt=0"`
+	RetryInterval    time.Duration `yaml:"retry_interval" toml:"retry_interval" env:"DB_RETRY_INTERVAL" validate:"gt=0"`
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" toml:"max_retry_interval" env:"DB_MAX_RETRY_INTERVAL" validate:"gtefield=RetryInterval"`
+	// AutoMigrate makes the server apply pending migrations at startup
+	// instead of leaving them to cmd/migrate
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+// PresenterConfig configures the business logic layer
+type PresenterConfig struct {
+	OperationTimeout time.Duration `yaml:"operation_timeout" toml:"operation_timeout" env:"OPERATION_TIMEOUT" validate:"gt=0"`
+	BulkWorkers      int           `yaml:"bulk_workers" toml:"bulk_workers" env:"BULK_WORKERS" validate:"min=1"`
+	ReservationTTL   time.Duration `yaml:"reservation_ttl" toml:"reservation_ttl" env:"RESERVATION_TTL" validate:"gt=0"`
+	// TrashRetention is how long deleted products stay restorable before
+	// they are purged for good. Zero, the default, keeps them forever, so
+	// purging is something operators opt in to.
+	TrashRetention time.Duration `yaml:"trash_retention" toml:"trash_retention" env:"TRASH_RETENTION" validate:"min=0"`
+}
+
+// IdempotencyConfig configures Idempotency-Key handling
+type IdempotencyConfig struct {
+	Store string        `yaml:"store" toml:"store" env:"IDEMPOTENCY_STORE" validate:"oneof=postgres memory"`
+	TTL   time.Duration `yaml:"ttl" toml:"ttl" env:"IDEMPOTENCY_TTL" validate:"gt=0"`
+}
+
+// HealthConfig configures the readiness checks
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" validate:"gt=0"`
+	// MaxInFlightWrites is the number of running presenter writes at which
+	// the service stops reporting ready
+	MaxInFlightWrites int `yaml:"max_in_flight_writes" toml:"max_in_flight_writes" env:"HEALTH_MAX_IN_FLIGHT_WRITES" validate:"min=1"`
+}
+
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" toml:"exporter" env:"OTEL_TRACES_EXPORTER" validate:"oneof=otlp stdout none"`
+	ServiceName string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+	// OTLPEndpoint is the collector URL, such as http://localhost:4318. The
+	// exporter's default is used when it is empty.
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"omitempty,url"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`
+}
+
+// Default returns the configuration used for anything that is not set
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            8080,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			CORSOrigins:     []string{"*"},
+		},
+		Database: DatabaseConfig{
+			Driver:           "postgres",
+			Port:             5432,
+			Path:             "products.db",
+			SSLMode:          "disable",
+			MaxOpenConns:     25,
+			MaxIdleConns:     5,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			ConnectTimeout:   time.Minute,
+			RetryInterval:    500 * time.Millisecond,
+			MaxRetryInterval: 10 * time.Second,
+		},
+		Presenters: PresenterConfig{
+			OperationTimeout: 30 * time.Second,
+			BulkWorkers:      8,
+			ReservationTTL:   10 * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			Store: "postgres",
+			TTL:   24 * time.Hour,
+		},
+		Health: HealthConfig{
+			CheckTimeout:      2 * time.Second,
+			MaxInFlightWrites: 1000,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "simple-goroutine-product",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// DSN returns the PostgreSQL connection string
+func (c DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Load reads the configuration. path names an optional YAML (.yaml, .yml)
+// or TOML (.toml) file; when it is empty the CONFIG_FILE environment
+// variable is used instead. The returned error describes every invalid
+// setting.
+func Load(path string) (*Config, error) {
+	// Variables already in the environment win over .env
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("config: read .env: %w", err)
+	}
+
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile decodes a YAML or TOML file over cfg
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config: unsupported file type %q, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the fields of v that have an env tag with the value of
+// that environment variable, when it is set
+func loadEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := loadEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			continue
+		}
+
+		if err := setField(field, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("config: invalid %s %q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+// setField parses value into a field of one of the supported types
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("expected true or false")
+		}
+		field.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("expected an integer")
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("expected a number")
+		}
+		field.SetFloat(f)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("expected a duration such as 30s or 5m")
+		}
+		field.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate checks every setting and reports all invalid ones by the
+// environment variable that sets them
+func (c *Config) Validate() error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("env")
+	})
+
+	err := v.Struct(c)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	messages := make([]string, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		messages[i] = fieldMessage(fe)
+	}
+	return fmt.Errorf("config: %s", strings.Join(messages, "; "))
+}
+
+// fieldMessage describes an invalid setting
+func fieldMessage(fe validator.FieldError) string {
+	name := fe.Field()
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", name)
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("%s is required when %s is %s", name, siblingEnv(fe, field), value)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s, got %q", name, strings.ReplaceAll(fe.Param(), " ", ", "), fe.Value())
+	case "ltefield":
+		return fmt.Sprintf("%s must not be greater than %s", name, siblingEnv(fe, fe.Param()))
+	case "gtefield":
+		return fmt.Sprintf("%s must not be less than %s", name, siblingEnv(fe, fe.Param()))
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", name, fe.Param())
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must not be empty", name)
+		}
+		return fmt.Sprintf("%s must be at least %s", name, fe.Param())
+	case "url":
+		return fmt.Sprintf("%s must be a URL, got %q", name, fe.Value())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", name, fe.Param())
+	default:
+		return fmt.Sprintf("%s failed the %s rule", name, fe.Tag())
+	}
+}
+
+// siblingEnv returns the environment variable of the field named by a
+// cross-field rule
+func siblingEnv(fe validator.FieldError, name string) string {
+	t := reflect.TypeOf(Config{})
+	parts := strings.Split(fe.StructNamespace(), ".")
+	for _, part := range parts[1 : len(parts)-1] {
+		field, _ := t.FieldByName(part)
+		t = field.Type
+	}
+
+	if field, ok := t.FieldByName(name); ok {
+		return field.Tag.Get("env")
+	}
+	return name
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setRequired sets the settings that have no default
+func setRequired(t *testing.T) {
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "products")
+}
+
+// writeFile writes a config file into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setRequired(t)
+
+	cfg, err := Load("")
+
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, []string{"*"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, "disable", cfg.Database.SSLMode)
+	assert.Equal(t, 30*time.Second, cfg.Presenters.OperationTimeout)
+	assert.Equal(t, "postgres", cfg.Idempotency.Store)
+	assert.False(t, cfg.Database.AutoMigrate)
+	assert.Zero(t, cfg.Presenters.TrashRetention)
+	assert.Zero(t, cfg.Server.ReadTimeout)
+	assert.Zero(t, cfg.Server.WriteTimeout)
+}
+
+func TestLoad_Env(t *testing.T) {
+	setRequired(t)
+	t.Setenv("APP_PORT", "9090")
+	t.Setenv("DB_SSLMODE", "require")
+	t.Setenv("OPERATION_TIMEOUT", "5s")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("DB_AUTO_MIGRATE", "true")
+	t.Setenv("TRASH_RETENTION", "720h")
+
+	cfg, err := Load("")
+
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, cfg.Presenters.TrashRetention)
+	assert.True(t, cfg.Database.AutoMigrate)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "require", cfg.Database.SSLMode)
+	assert.Equal(t, 5*time.Second, cfg.Presenters.OperationTimeout)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Server.CORSOrigins)
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 3000
+  cors_origins: [https://shop.example]
+database:
+  host: db
+  user: app
+  name: products
+  max_open_conns: 50
+presenters:
+  operation_timeout: 10s
+`)
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3000, cfg.Server.Port)
+	assert.Equal(t, []string{"https://shop.example"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "db", cfg.Database.Host)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 10*time.Second, cfg.Presenters.OperationTimeout)
+	// Settings missing from the file keep their defaults
+	assert.Equal(t, 5432, cfg.Database.Port)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.toml", `
+[database]
+host = "db"
+user = "app"
+name = "products"
+ssl_mode = "verify-full"
+
+[idempotency]
+store = "memory"
+`))
+
+	cfg, err := Load("")
+
+	require.NoError(t, err)
+	assert.Equal(t, "db", cfg.Database.Host)
+	assert.Equal(t, "verify-full", cfg.Database.SSLMode)
+	assert.Equal(t, "memory", cfg.Idempotency.Store)
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yml", `
+database:
+  host: db
+  user: app
+  name: products
+`)
+	t.Setenv("DB_HOST", "replica")
+
+	cfg, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "replica", cfg.Database.Host)
+	assert.Equal(t, "app", cfg.Database.User)
+}
+
+func TestLoad_ValidationErrors(t *testing.T) {
+	t.Setenv("DB_HOST", "")
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "products")
+	t.Setenv("DB_SSLMODE", "sometimes")
+	t.Setenv("BULK_WORKERS", "0")
+	t.Setenv("DB_MAX_IDLE_CONNS", "50")
+
+	_, err := Load("")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DB_HOST is required")
+	assert.Contains(t, err.Error(), `DB_SSLMODE must be one of disable, allow, prefer, require, verify-ca, verify-full, got "sometimes"`)
+	assert.Contains(t, err.Error(), "BULK_WORKERS must be at least 1")
+	assert.Contains(t, err.Error(), "DB_MAX_IDLE_CONNS must not be greater than DB_MAX_OPEN_CONNS")
+}
+
+func TestLoad_SQLiteDriver(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+
+	cfg, err := Load("")
+
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Database.Driver)
+	assert.Equal(t, "products.db", cfg.Database.Path)
+
+	t.Setenv("DB_PATH", "")
+	_, err = Load("")
+
+	assert.ErrorContains(t, err, "DB_PATH is required when DB_DRIVER is sqlite")
+}
+
+func TestLoad_InvalidValue(t *testing.T) {
+	setRequired(t)
+	t.Setenv("OPERATION_TIMEOUT", "soon")
+
+	_, err := Load("")
+
+	assert.EqualError(t, err, `config: invalid OPERATION_TIMEOUT "soon": expected a duration such as 30s or 5m`)
+}
+
+func TestLoad_UnsupportedFile(t *testing.T) {
+	_, err := Load(writeFile(t, "config.json", "{}"))
+
+	assert.ErrorContains(t, err, "unsupported file type")
+}
+
+func TestDatabaseConfig_DSN(t *testing.T) {
+	cfg := DatabaseConfig{Host: "db", Port: 5432, User: "app", Password: "p@ss word", Name: "products", SSLMode: "require"}
+
+	assert.Equal(t, "postgres://app:p%40ss%20word@db:5432/products?sslmode=require", cfg.DSN())
+}
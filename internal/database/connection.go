@@ -1,45 +1,127 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/config"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// DB holds the database connection
-var DB *gorm.DB
+// ConnectDatabase opens the database connection pool. While the database is
+// unreachable it retries with exponential backoff, starting at
+// cfg.RetryInterval and capped at cfg.MaxRetryInterval, until
+// cfg.ConnectTimeout elapses or ctx is cancelled.
+func ConnectDatabase(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := dialect(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
 
-// ConnectDatabase initializes the database connection
-func ConnectDatabase() {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
+	var db *gorm.DB
+	err = retry(ctx, cfg.RetryInterval, cfg.MaxRetryInterval, func() error {
+		var err error
+		db, err = open(ctx, dialector, cfg)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	log.Println("Database connected successfully")
+	return db, nil
+}
 
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// open opens a connection pool and checks that the database answers
+func open(ctx context.Context, dialector gorm.Dialector, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// Ping below instead, so the check honours ctx
+		DisableAutomaticPing: true,
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, err
 	}
 
-	// Auto migrate the schema
-	err = database.AutoMigrate(&models.Product{})
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if cfg.Driver == "sqlite" {
+		// SQLite allows a single writer, and every connection to ":memory:"
+		// opens a separate database, so keep exactly one connection open
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
 	}
 
-	DB = database
-	log.Println("Database connected successfully")
+	if err := Ping(ctx, db); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
-// GetDB returns the database instance
-func GetDB() *gorm.DB {
-	return DB
+// dialect returns the GORM dialector for the configured driver
+func dialect(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "postgres":
+		return postgres.Open(cfg.DSN()), nil
+	case "sqlite":
+		return sqlite.Open(cfg.Path), nil
+	default:
+		return nil, fmt.Errorf("driver %q does not use a database", cfg.Driver)
+	}
+}
+
+// retry calls attempt until it succeeds, doubling the wait between attempts
+// from initial up to max. It gives up with the last error once ctx is done.
+func retry(ctx context.Context, initial, max time.Duration, attempt func() error) error {
+	delay := initial
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("gave up after %d attempts: %w", n, err)
+		}
+
+		log.Printf("Database not ready (attempt %d), retrying in %s: %v", n, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", n, err)
+		}
+		delay = min(delay*2, max)
+	}
+}
+
+// Ping reports whether the database is reachable, for readiness checks
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CloseDatabase closes the database connection pool
+func CloseDatabase(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
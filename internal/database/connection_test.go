@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_SucceedsAfterFailures(t *testing.T) {
+	attempts := 0
+	err := retry(context.Background(), time.Millisecond, 4*time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetry_GivesUpWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	refused := errors.New("connection refused")
+	attempts := 0
+	start := time.Now()
+	err := retry(ctx, time.Millisecond, 5*time.Millisecond, func() error {
+		attempts++
+		return refused
+	})
+
+	assert.ErrorIs(t, err, refused)
+	assert.ErrorContains(t, err, "gave up after")
+	assert.Greater(t, attempts, 1)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetry_BacksOffExponentially(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var gaps []time.Duration
+	last := time.Now()
+	_ = retry(ctx, 10*time.Millisecond, 40*time.Millisecond, func() error {
+		now := time.Now()
+		gaps = append(gaps, now.Sub(last))
+		last = now
+		if len(gaps) == 5 {
+			cancel()
+		}
+		return errors.New("connection refused")
+	})
+
+	// Waits of 10ms, 20ms, 40ms and 40ms (capped) precede attempts 2 to 5
+	assert.Len(t, gaps, 5)
+	assert.GreaterOrEqual(t, gaps[1], 10*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[2], 20*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[3], 40*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[4], 40*time.Millisecond)
+}
@@ -0,0 +1,493 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the SQL migrations, one directory per dialect
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Dialects lists the dialects migrations are written for, named after the
+// GORM dialector
+var Dialects = []string{"postgres", "sqlite"}
+
+// migrationLockKey identifies the PostgreSQL advisory lock held while
+// migrating, so concurrent replicas take turns
+const migrationLockKey = 4_815_162_342
+
+// migrationFile matches names such as 0002_create_reservations.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change with the SQL to apply and revert it
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String returns the migration's file name prefix, e.g. 0002_create_reservations
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Unknown is set for applied versions without a migration file, such
+	// as ones applied by a newer build
+	Unknown bool
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName overrides the table name used by appliedMigration
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and reverts the SQL migrations of a database. Each
+// migration runs in its own transaction together with its schema_migrations
+// row, so a failed migration leaves no trace.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the embedded migrations of db's dialect
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	dir, err := fs.Sub(migrationFiles, "migrations/"+dialect)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db, dir)
+}
+
+// newMigrator creates a migrator for the migrations in dir
+func newMigrator(db *gorm.DB, dir fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %q", db.Dialector.Name())
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads the migrations in dir, ordered by version. Every
+// version needs both an up and a down file.
+func loadMigrations(dir fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 0)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", entry.Name())
+		}
+		data, err := fs.ReadFile(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is also used by %s", entry.Name(), version, migration)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				if err := m.apply(db, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the n most recently applied migrations, or every applied
+// migration if there are fewer
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 1 {
+		return errors.New("the number of migrations to revert must be at least 1")
+	}
+
+	return m.locked(ctx, func(db *gorm.DB) error {
+		versions, err := m.appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n && i < len(versions); i++ {
+			if err := m.revert(db, versions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Goto applies or reverts migrations until exactly the migrations up to
+// version are applied. Version 0 reverts every migration.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	if err := m.checkVersion(version); err != nil {
+		return err
+	}
+
+	return m.locked(ctx, func(db *gorm.DB) error {
+		versions, err := m.appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for _, applied := range versions {
+			if applied > version {
+				if err := m.revert(db, applied); err != nil {
+					return err
+				}
+			}
+		}
+
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(db, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Force records the migrations up to version as applied and every later one
+// as pending without running any SQL. It adopts a schema created some other
+// way, such as by hand after a failed migration.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if err := m.checkVersion(version); err != nil {
+		return err
+	}
+
+	return m.locked(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("version > ?", version).Delete(&appliedMigration{}).Error; err != nil {
+				return err
+			}
+
+			applied, err := m.applied(tx)
+			if err != nil {
+				return err
+			}
+			for _, migration := range m.migrations {
+				if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+					if err := tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			log.Printf("Forced schema to version %d", version)
+			return nil
+		})
+	})
+}
+
+// Status reports every migration and whether it has been applied, ordered
+// by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var records []appliedMigration
+	db := m.db.WithContext(ctx)
+	if db.Migrator().HasTable(&appliedMigration{}) {
+		if err := db.Order("version").Find(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	applied := make(map[uint]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: record.AppliedAt})
+		delete(applied, migration.Version)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Migration: Migration{Version: record.Version, Name: record.Name},
+			Applied:   true,
+			AppliedAt: record.AppliedAt,
+			Unknown:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// checkVersion fails unless version is 0 or the version of a migration
+func (m *Migrator) checkVersion(version uint) error {
+	if version == 0 {
+		return nil
+	}
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("there is no migration with version %d", version)
+}
+
+// locked runs fn on a single connection while holding the migration lock,
+// after making sure the schema_migrations table exists
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	db := m.db.Session(&gorm.Session{NewDB: true, Context: ctx})
+	db.Statement.ConnPool = conn
+
+	// SQLite allows a single writer, so only PostgreSQL needs the lock
+	if db.Dialector.Name() == "postgres" {
+		var acquired bool
+		if err := db.Raw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			log.Println("Waiting for another migration to finish")
+			if err := db.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+		}
+		defer db.Session(&gorm.Session{Context: context.Background()}).Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+	}
+
+	err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+	if err != nil {
+		return err
+	}
+
+	return fn(db)
+}
+
+// applied returns the applied migrations by version
+func (m *Migrator) applied(db *gorm.DB) (map[uint]appliedMigration, error) {
+	var records []appliedMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// appliedVersions returns the applied versions, most recent first
+func (m *Migrator) appliedVersions(db *gorm.DB) ([]uint, error) {
+	var versions []uint
+	err := db.Model(&appliedMigration{}).Order("version DESC").Pluck("version", &versions).Error
+	return versions, err
+}
+
+// apply runs a migration's up SQL and records it
+func (m *Migrator) apply(db *gorm.DB, migration Migration) error {
+	log.Printf("Applying migration %s", migration)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+// revert runs the down SQL of an applied version and forgets it
+func (m *Migrator) revert(db *gorm.DB, version uint) error {
+	var migration *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			migration = &m.migrations[i]
+		}
+	}
+	if migration == nil {
+		return fmt.Errorf("revert migration %d: there is no migration file for it", version)
+	}
+
+	log.Printf("Reverting migration %s", migration)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&appliedMigration{Version: version}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+// Migrate applies every pending migration
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(ctx)
+}
+
+// CheckSchema reports whether every migration has been applied, for
+// readiness checks. Versions applied by a newer build are accepted so
+// replicas keep serving during a rolling upgrade.
+func CheckSchema(ctx context.Context, db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations pending, starting with %s, run the migrations", len(pending), pending[0])
+	}
+	return nil
+}
+
+// CreateMigration writes empty up and down files for a new migration in
+// every dialect directory under dir, numbered after the latest migration of
+// any dialect. It returns the paths of the created files.
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("the migration name must contain letters or digits")
+	}
+
+	var latest uint
+	for _, dialect := range Dialects {
+		migrations, err := loadMigrations(os.DirFS(filepath.Join(dir, dialect)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, migration := range migrations {
+			latest = max(latest, migration.Version)
+		}
+	}
+
+	migration := Migration{Version: latest + 1, Name: name}
+	var paths []string
+	for _, dialect := range Dialects {
+		if err := os.MkdirAll(filepath.Join(dir, dialect), 0o755); err != nil {
+			return paths, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%s.%s.sql", migration, direction))
+			content := fmt.Sprintf("-- %s (%s, %s)\n", migration, dialect, direction)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,19 @@
+-- The table as created by the server's original AutoMigrate, which is
+-- adopted as it is when it already exists
+CREATE TABLE IF NOT EXISTS products (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT,
+    price       DECIMAL NOT NULL,
+    stock       BIGINT DEFAULT 0,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Make sure an adopted table has every column
+SELECT id, name, description, price, stock, version, created_at, updated_at, deleted_at FROM products WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reservations (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    quantity   BIGINT NOT NULL,
+    status     VARCHAR(16) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+-- Make sure an adopted table has every column
+SELECT id, product_id, quantity, status, expires_at, created_at, updated_at FROM reservations WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_reservations_product_status ON reservations (product_id, status);
+CREATE INDEX IF NOT EXISTS idx_reservations_status_expires ON reservations (status, expires_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          VARCHAR(255) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code  BIGINT NOT NULL DEFAULT 0,
+    content_type VARCHAR(255),
+    body         BYTEA,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ
+);
+
+-- Make sure an adopted table has every column
+SELECT key, request_hash, status_code, content_type, body, expires_at, created_at FROM idempotency_keys WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS product_audit_entries (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    action     VARCHAR(16) NOT NULL,
+    actor      VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    changes    TEXT NOT NULL,
+    version    BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Make sure an adopted table has every column
+SELECT id, product_id, action, actor, request_id, changes, version, created_at FROM product_audit_entries WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_product_audit_entries_product ON product_audit_entries (product_id);
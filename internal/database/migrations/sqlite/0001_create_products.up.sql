@@ -0,0 +1,21 @@
+-- The table as created by the server's original AutoMigrate, which is
+-- adopted as it is when it already exists
+CREATE TABLE IF NOT EXISTS products (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    name        TEXT NOT NULL,
+    description TEXT,
+    price       REAL NOT NULL,
+    stock       INTEGER DEFAULT 0,
+    created_at  DATETIME,
+    updated_at  DATETIME,
+    deleted_at  DATETIME
+);
+
+-- SQLite has no ADD COLUMN IF NOT EXISTS. Neither the table created above
+-- nor the original one has the column, so adding it is safe.
+ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Make sure an adopted table has every column
+SELECT id, name, description, price, stock, version, created_at, updated_at, deleted_at FROM products WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
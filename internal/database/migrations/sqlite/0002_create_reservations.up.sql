@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reservations (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    quantity   INTEGER NOT NULL,
+    status     TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+-- Make sure an adopted table has every column
+SELECT id, product_id, quantity, status, expires_at, created_at, updated_at FROM reservations WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_reservations_product_status ON reservations (product_id, status);
+CREATE INDEX IF NOT EXISTS idx_reservations_status_expires ON reservations (status, expires_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS product_audit_entries (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    action     TEXT NOT NULL,
+    actor      TEXT NOT NULL,
+    request_id TEXT,
+    changes    TEXT NOT NULL,
+    version    INTEGER NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- Make sure an adopted table has every column
+SELECT id, product_id, action, actor, request_id, changes, version, created_at FROM product_audit_entries WHERE 1 = 0;
+
+CREATE INDEX IF NOT EXISTS idx_product_audit_entries_product ON product_audit_entries (product_id);
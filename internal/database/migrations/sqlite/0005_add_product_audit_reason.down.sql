@@ -0,0 +1 @@
+ALTER TABLE product_audit_entries DROP COLUMN reason;
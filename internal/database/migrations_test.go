@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"simple-goroutine-product/internal/config"
+	"simple-goroutine-product/internal/models"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// openSQLite connects to an empty in-memory SQLite database
+func openSQLite(t *testing.T) *gorm.DB {
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = ":memory:"
+
+	db, err := ConnectDatabase(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { CloseDatabase(db) })
+	return db
+}
+
+// appliedVersions lists the versions Status reports as applied
+func appliedVersions(t *testing.T, migrator *Migrator) []uint {
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+
+	versions := []uint{}
+	for _, status := range statuses {
+		if status.Applied {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func TestMigrator_UpDownAndGoto(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, CheckSchema(ctx, db), "5 migrations pending, starting with 0001_create_products")
+
+	require.NoError(t, migrator.Up(ctx))
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, appliedVersions(t, migrator))
+	assert.True(t, db.Migrator().HasTable("idempotency_keys"))
+	assert.True(t, db.Migrator().HasTable("product_audit_entries"))
+	assert.True(t, db.Migrator().HasColumn("product_audit_entries", "reason"))
+	assert.True(t, db.Migrator().HasIndex("reservations", "idx_reservations_status_expires"))
+	assert.NoError(t, CheckSchema(ctx, db))
+
+	require.NoError(t, migrator.Down(ctx, 3))
+	assert.Equal(t, []uint{1, 2}, appliedVersions(t, migrator))
+	assert.False(t, db.Migrator().HasTable("idempotency_keys"))
+	assert.False(t, db.Migrator().HasTable("product_audit_entries"))
+	assert.ErrorContains(t, CheckSchema(ctx, db), "3 migrations pending")
+
+	require.NoError(t, migrator.Goto(ctx, 0))
+	assert.Equal(t, []uint{}, appliedVersions(t, migrator))
+	assert.False(t, db.Migrator().HasTable("products"))
+
+	require.NoError(t, migrator.Goto(ctx, 2))
+	assert.Equal(t, []uint{1, 2}, appliedVersions(t, migrator))
+	assert.True(t, db.Migrator().HasTable("reservations"))
+
+	assert.ErrorContains(t, migrator.Goto(ctx, 42), "there is no migration with version 42")
+	assert.Error(t, migrator.Down(ctx, 0))
+}
+
+func TestMigrator_Force(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	// Recording versions runs no SQL
+	require.NoError(t, migrator.Force(ctx, 2))
+	assert.Equal(t, []uint{1, 2}, appliedVersions(t, migrator))
+	assert.False(t, db.Migrator().HasTable("products"))
+
+	require.NoError(t, migrator.Force(ctx, 1))
+	assert.Equal(t, []uint{1}, appliedVersions(t, migrator))
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := newMigrator(db, fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER); CREATE TABLE broken (;")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	require.NoError(t, err)
+
+	err = migrator.Up(ctx)
+
+	assert.ErrorContains(t, err, "apply migration 0002_create_b")
+	assert.Equal(t, []uint{1}, appliedVersions(t, migrator))
+	assert.True(t, db.Migrator().HasTable("a"))
+	assert.False(t, db.Migrator().HasTable("b"))
+}
+
+// baselineProduct is the product model of the original server, whose
+// AutoMigrate created the products table
+type baselineProduct struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	Description string
+	Price       float64 `gorm:"not null"`
+	Stock       int     `gorm:"default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// TableName overrides the table name used by baselineProduct
+func (baselineProduct) TableName() string {
+	return "products"
+}
+
+// testAdoptsBaseline migrates db, which holds the products table of the
+// original server with a product in it
+func testAdoptsBaseline(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	require.NoError(t, db.AutoMigrate(&baselineProduct{}))
+	require.NoError(t, db.Create(&baselineProduct{Name: "Keyboard", Price: 49.9, Stock: 10}).Error)
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.Up(ctx))
+
+	var product models.Product
+	require.NoError(t, db.First(&product).Error)
+	assert.Equal(t, "Keyboard", product.Name)
+	assert.Equal(t, 10, product.Stock)
+	assert.Equal(t, uint(1), product.Version)
+	assert.NoError(t, CheckSchema(ctx, db))
+}
+
+// TestMigrator_AdoptsBaselinePostgres runs against the database named by
+// TEST_POSTGRES_DSN, dropping every table first
+func TestMigrator_AdoptsBaselinePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() { CloseDatabase(db) })
+	require.NoError(t, db.Exec("DROP TABLE IF EXISTS products, reservations, idempotency_keys, product_audit_entries, schema_migrations").Error)
+
+	testAdoptsBaseline(t, db)
+}
+
+func TestMigrator_AdoptsExistingTables(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Baseline", func(t *testing.T) {
+		testAdoptsBaseline(t, openSQLite(t))
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		db := openSQLite(t)
+		require.NoError(t, db.Exec("CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT, price REAL, stock INTEGER)").Error)
+		migrator, err := NewMigrator(db)
+		require.NoError(t, err)
+
+		err = migrator.Up(ctx)
+
+		assert.ErrorContains(t, err, "apply migration 0001_create_products")
+		assert.ErrorContains(t, err, "description")
+		assert.Equal(t, []uint{}, appliedVersions(t, migrator))
+	})
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"0001_create_a.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+	})
+	assert.ErrorContains(t, err, "0001_create_a needs both an up and a down file")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"0001_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	})
+	assert.ErrorContains(t, err, "version 1 is also used by")
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sqlite"), 0o755))
+	for _, name := range []string{"0007_create_a.up.sql", "0007_create_a.down.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "sqlite", name), []byte("SELECT 1;"), 0o644))
+	}
+
+	paths, err := CreateMigration(dir, "Add product SKU")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "postgres", "0008_add_product_sku.up.sql"),
+		filepath.Join(dir, "postgres", "0008_add_product_sku.down.sql"),
+		filepath.Join(dir, "sqlite", "0008_add_product_sku.up.sql"),
+		filepath.Join(dir, "sqlite", "0008_add_product_sku.down.sql"),
+	}, paths)
+
+	migrations, err := loadMigrations(os.DirFS(filepath.Join(dir, "postgres")))
+	require.NoError(t, err)
+	assert.Equal(t, "0008_add_product_sku", migrations[0].String())
+}
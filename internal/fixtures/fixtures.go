@@ -0,0 +1,160 @@
+// Package fixtures loads product catalogs from fixture files or generates
+// them, and seeds them into a ProductRepository. The seed command and test
+// packages share it.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"simple-goroutine-product/internal/validators"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the content of a fixture file
+type Fixture struct {
+	Products []models.ProductRequest `json:"products" yaml:"products"`
+}
+
+// Result counts what Seed did with each product
+type Result struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// seedBatchSize is the number of products written per repository call
+const seedBatchSize = 500
+
+// LoadFile reads a YAML or JSON fixture file
+func LoadFile(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse decodes and validates a fixture. The extension of name picks the
+// format, .yaml, .yml or .json. Unknown keys are rejected so that typos do
+// not silently drop data.
+func Parse(data []byte, name string) (*Fixture, error) {
+	var fixture Fixture
+	var err error
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&fixture)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	default:
+		return nil, fmt.Errorf("fixtures: unsupported file type %q, expected .yaml, .yml or .json", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: parse %s: %w", name, err)
+	}
+
+	validator := validators.NewValidator()
+	for i, product := range fixture.Products {
+		if err := validator.Validate(product); err != nil {
+			return nil, fmt.Errorf("fixtures: %s: products[%d]: %w", name, i, err)
+		}
+	}
+	return &fixture, nil
+}
+
+// Seed writes products to repo in a single transaction. Products are
+// matched to stored ones by name, so seeding the same catalog twice changes
+// nothing: new names are created, stored products whose fields differ are
+// updated and the rest are left alone. With truncate every stored product
+// and reservation is removed first.
+func Seed(ctx context.Context, repo repositories.ProductRepository, products []models.ProductRequest, truncate bool) (*Result, error) {
+	names := make(map[string]bool, len(products))
+	for _, product := range products {
+		if names[product.Name] {
+			return nil, fmt.Errorf("fixtures: product %q appears more than once", product.Name)
+		}
+		names[product.Name] = true
+	}
+
+	result := &Result{}
+	err := repo.Transaction(ctx, func(repo repositories.ProductRepository) error {
+		if truncate {
+			if err := repo.Truncate(ctx); err != nil {
+				return err
+			}
+		}
+
+		// The first stored product with a name is the one a fixture updates
+		stored := make(map[string]models.Product)
+		err := repo.Stream(ctx, models.ProductQuery{}, func(product *models.Product) error {
+			if _, ok := stored[product.Name]; !ok && names[product.Name] {
+				stored[product.Name] = *product
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var batch []models.Product
+		for _, request := range products {
+			product := models.Product{
+				Name:        request.Name,
+				Description: request.Description,
+				Price:       request.Price,
+				Stock:       request.Stock,
+			}
+
+			if existing, ok := stored[request.Name]; ok {
+				if existing.Description == product.Description && existing.Price == product.Price && existing.Stock == product.Stock {
+					result.Unchanged++
+					continue
+				}
+				product.ID = existing.ID
+				result.Updated++
+			} else {
+				result.Created++
+			}
+
+			batch = append(batch, product)
+			if len(batch) == seedBatchSize {
+				if err := upsert(ctx, repo, batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return upsert(ctx, repo, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// upsert writes a batch, failing if a product could not be written
+func upsert(ctx context.Context, repo repositories.ProductRepository, batch []models.Product) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	rejected, err := repo.UpsertBatch(ctx, batch)
+	if err != nil {
+		return err
+	}
+	for i, err := range rejected {
+		return fmt.Errorf("fixtures: product %q: %w", batch[i].Name, err)
+	}
+	return nil
+}
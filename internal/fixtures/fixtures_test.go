@@ -0,0 +1,117 @@
+package fixtures
+
+import (
+	"context"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listProducts returns every product in repo in ID order
+func listProducts(t *testing.T, repo repositories.ProductRepository) []models.Product {
+	products, _, err := repo.GetAll(context.Background(), models.ProductQuery{Page: 1, Limit: 1000})
+	require.NoError(t, err)
+	return products
+}
+
+func TestLoadFile(t *testing.T) {
+	expected := []models.ProductRequest{
+		{Name: "Keyboard", Description: "Mechanical, US layout", Price: 49.9, Stock: 10},
+		{Name: "Mouse", Price: 19.5, Stock: 5},
+	}
+
+	for _, path := range []string{"testdata/catalog.yaml", "testdata/catalog.json"} {
+		fixture, err := LoadFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, fixture.Products, path)
+	}
+}
+
+func TestLoadFile_DemoCatalog(t *testing.T) {
+	fixture, err := LoadFile("../../fixtures/demo.yaml")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, fixture.Products)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("products:\n  - name: Keyboard\n    prize: 10\n"), "catalog.yaml")
+	assert.ErrorContains(t, err, "field prize not found")
+
+	_, err = Parse([]byte(`{"products": [{"name": "Keyboard", "price": -1}]}`), "catalog.json")
+	assert.ErrorContains(t, err, "catalog.json: products[0]")
+	assert.ErrorIs(t, err, repositories.ErrValidation)
+
+	_, err = Parse([]byte("name,price\n"), "catalog.csv")
+	assert.ErrorContains(t, err, "unsupported file type")
+}
+
+func TestGenerate(t *testing.T) {
+	products := Generate(2000, 42)
+
+	require.Len(t, products, 2000)
+	assert.Equal(t, products, Generate(2000, 42))
+	assert.NotEqual(t, products[:10], Generate(10, 7))
+
+	names := make(map[string]bool)
+	for _, product := range products {
+		assert.False(t, names[product.Name], "duplicate name %q", product.Name)
+		names[product.Name] = true
+		assert.NotEmpty(t, product.Description)
+		assert.Greater(t, product.Price, 0.0)
+		assert.GreaterOrEqual(t, product.Stock, 0)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryProductRepository(repositories.NewMemoryStore())
+	products := Generate(20, 1)
+
+	result, err := Seed(ctx, repo, products, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Created: 20}, result)
+
+	// Seeding again matches every product by name
+	result, err = Seed(ctx, repo, products, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Unchanged: 20}, result)
+
+	products[3].Stock++
+	products = append(products, models.ProductRequest{Name: "Keyboard", Price: 49.9})
+	result, err = Seed(ctx, repo, products, false)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Created: 1, Updated: 1, Unchanged: 19}, result)
+
+	stored := listProducts(t, repo)
+	require.Len(t, stored, 21)
+	assert.Equal(t, products[3].Stock, stored[3].Stock)
+	assert.Equal(t, uint(2), stored[3].Version)
+}
+
+func TestSeed_Truncate(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryProductRepository(repositories.NewMemoryStore())
+	require.NoError(t, repo.Create(ctx, &models.Product{Name: "Leftover", Price: 1, Version: 1}))
+
+	result, err := Seed(ctx, repo, Generate(3, 1), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Created: 3}, result)
+	stored := listProducts(t, repo)
+	require.Len(t, stored, 3)
+	assert.NotEqual(t, "Leftover", stored[0].Name)
+}
+
+func TestSeed_DuplicateNames(t *testing.T) {
+	repo := repositories.NewMemoryProductRepository(repositories.NewMemoryStore())
+	products := []models.ProductRequest{{Name: "Keyboard", Price: 1}, {Name: "Keyboard", Price: 2}}
+
+	_, err := Seed(context.Background(), repo, products, false)
+
+	assert.ErrorContains(t, err, `product "Keyboard" appears more than once`)
+	assert.Empty(t, listProducts(t, repo))
+}
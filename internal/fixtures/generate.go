@@ -0,0 +1,59 @@
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"simple-goroutine-product/internal/models"
+	"strings"
+)
+
+// Word lists the generated product names are built from
+var (
+	adjectives = []string{
+		"Compact", "Ergonomic", "Wireless", "Premium", "Portable", "Rugged",
+		"Classic", "Smart", "Lightweight", "Heavy-Duty", "Modular", "Silent",
+	}
+	materials = []string{
+		"Aluminium", "Bamboo", "Carbon", "Ceramic", "Cotton", "Glass",
+		"Leather", "Oak", "Recycled", "Steel", "Titanium", "Wool",
+	}
+	nouns = []string{
+		"Backpack", "Desk Lamp", "Headphones", "Keyboard", "Kettle", "Monitor Stand",
+		"Mouse", "Notebook", "Speaker", "Tumbler", "Umbrella", "Water Bottle",
+	}
+	features = []string{
+		"built to last", "easy to clean", "backed by a two year warranty",
+		"designed for everyday use", "made from sustainable materials",
+		"shipped in plastic-free packaging", "loved by remote workers",
+	}
+)
+
+// Generate returns n realistic fake products. The same n and seed always
+// give the same products, so seeding a generated catalog twice is a no-op.
+// Names are unique; once every word combination is used a number is added.
+func Generate(n int, seed int64) []models.ProductRequest {
+	random := rand.New(rand.NewSource(seed))
+	products := make([]models.ProductRequest, 0, n)
+	used := make(map[string]int, n)
+
+	for len(products) < n {
+		adjective := adjectives[random.Intn(len(adjectives))]
+		material := materials[random.Intn(len(materials))]
+		noun := nouns[random.Intn(len(nouns))]
+
+		name := fmt.Sprintf("%s %s %s", adjective, material, noun)
+		used[name]++
+		if count := used[name]; count > 1 {
+			name = fmt.Sprintf("%s %d", name, count)
+		}
+
+		products = append(products, models.ProductRequest{
+			Name:        name,
+			Description: fmt.Sprintf("A %s %s in %s, %s.", strings.ToLower(adjective), strings.ToLower(noun), strings.ToLower(material), features[random.Intn(len(features))]),
+			Price:       math.Round((4.99+random.Float64()*495)*100) / 100,
+			Stock:       random.Intn(500),
+		})
+	}
+	return products
+}
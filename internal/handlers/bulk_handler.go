@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+
+	"github.com/labstack/echo/v4"
+)
+
+// BulkHandler handles HTTP requests for bulk product operations
+type BulkHandler struct {
+	presenter presenters.BulkPresenter
+}
+
+// NewBulkHandler creates a new bulk handler
+func NewBulkHandler(presenter presenters.BulkPresenter) *BulkHandler {
+	return &BulkHandler{
+		presenter: presenter,
+	}
+}
+
+// ProcessBulk godoc
+// @Summary Bulk create, update and delete products
+// @Description Apply up to 5000 operations in one request. Best-effort batches are processed concurrently by a worker pool; atomic batches run in a single transaction and are rolled back if any operation fails.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param bulk body models.BulkRequest true "Bulk operations"
+// @Success 200 {object} models.BulkResponse
+// @Success 207 {object} models.BulkResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/bulk [post]
+func (h *BulkHandler) ProcessBulk(c echo.Context) error {
+	var req models.BulkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	}
+
+	// Operations are validated one by one so each can fail on its own
+	if len(req.Operations) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "At least one operation is required")
+	}
+	if len(req.Operations) > models.MaxBulkOperations {
+		return echo.NewHTTPError(http.StatusBadRequest, "Too many operations")
+	}
+
+	response, err := h.presenter.ProcessBulk(c.Request().Context(), req, c.Validate)
+	if err != nil {
+		return err
+	}
+
+	if response.Failed > 0 {
+		return c.JSON(http.StatusMultiStatus, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"simple-goroutine-product/internal/validators"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the media type of RFC 7807 problem responses
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem types for the domain error kinds. Errors of any other kind use
+// "about:blank", whose title is the HTTP status text.
+const (
+	problemNotFound   = "/problems/not-found"
+	problemConflict   = "/problems/conflict"
+	problemValidation = "/problems/validation"
+	problemTimeout    = "/problems/timeout"
+	problemBlank      = "about:blank"
+)
+
+// HTTPErrorHandler is the central Echo error handler. It maps the domain
+// errors returned by the presenters to HTTP status codes and renders every
+// error as an RFC 7807 problem.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	problem := newProblem(err)
+	problem.Instance = c.Request().URL.Path
+	problem.RequestID = requestID(c)
+
+	// Field messages follow the client's preferred language
+	var validationErr *validators.ValidationError
+	if errors.As(err, &validationErr) {
+		var locale string
+		problem.Errors, locale = validationErr.Localize(c.Request().Header.Get("Accept-Language"))
+		c.Response().Header().Set("Content-Language", locale)
+	}
+	if problem.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		log.Println("Failed to write error response:", err)
+	}
+}
+
+// newProblem builds the problem describing an error
+func newProblem(err error) models.Problem {
+	var validationErr *validators.ValidationError
+	var httpErr *echo.HTTPError
+
+	problem := models.Problem{Type: problemBlank, Detail: err.Error()}
+	switch {
+	case errors.As(err, &validationErr):
+		problem.Type = problemValidation
+		problem.Status = http.StatusBadRequest
+		if errors.Is(err, presenters.ErrUnprocessablePatch) {
+			problem.Status = http.StatusUnprocessableEntity
+		}
+		problem.Detail = "The request failed validation"
+		problem.Errors = validationErr.Fields
+	case errors.As(err, &httpErr):
+		problem.Status = httpErr.Code
+		problem.Detail = fmt.Sprint(httpErr.Message)
+	case errors.Is(err, presenters.ErrNotFound):
+		problem.Type = problemNotFound
+		problem.Status = http.StatusNotFound
+	case errors.Is(err, presenters.ErrConflict):
+		problem.Type = problemConflict
+		problem.Status = http.StatusConflict
+	case errors.Is(err, presenters.ErrUnprocessablePatch):
+		problem.Type = problemValidation
+		problem.Status = http.StatusUnprocessableEntity
+	case errors.Is(err, presenters.ErrValidation):
+		problem.Type = problemValidation
+		problem.Status = http.StatusBadRequest
+	case errors.Is(err, presenters.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		problem.Type = problemTimeout
+		problem.Status = http.StatusGatewayTimeout
+	default:
+		// Unexpected errors are logged but not shown to clients
+		problem.Status = http.StatusInternalServerError
+		problem.Detail = ""
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+	return problem
+}
+
+// requestID returns the ID assigned to the request by the RequestID
+// middleware, falling back to the one the client sent
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
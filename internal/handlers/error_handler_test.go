@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"simple-goroutine-product/internal/validators"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedCode   int
+		expectedType   string
+		expectedDetail string
+	}{
+		{presenters.ErrNotFound, http.StatusNotFound, "/problems/not-found", "not found"},
+		{presenters.ErrVersionConflict, http.StatusConflict, "/problems/conflict", "product version conflict"},
+		{presenters.ErrInsufficientStock, http.StatusConflict, "/problems/conflict", "insufficient stock"},
+		{fmt.Errorf("%w: bad op", presenters.ErrInvalidPatch), http.StatusBadRequest, "/problems/validation", "invalid patch: bad op"},
+		{fmt.Errorf("%w: stock cannot be null or removed", presenters.ErrUnprocessablePatch), http.StatusUnprocessableEntity, "/problems/validation", "patch cannot be applied to the product: stock cannot be null or removed"},
+		{presenters.ErrTimeout, http.StatusGatewayTimeout, "/problems/timeout", "operation timeout"},
+		{echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match does not match"), http.StatusPreconditionFailed, "about:blank", "If-Match does not match"},
+		{echo.ErrNotFound, http.StatusNotFound, "about:blank", ""},
+		{errors.New("connection refused"), http.StatusInternalServerError, "about:blank", ""},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		httpReq := httptest.NewRequest(http.MethodGet, "/api/v1/products/1", nil)
+		httpReq.Header.Set(echo.HeaderXRequestID, "req-1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+
+		// Test
+		HTTPErrorHandler(tt.err, c)
+
+		// Assertions
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%v: expected status code %d, got %d", tt.err, tt.expectedCode, rec.Code)
+		}
+
+		if contentType := rec.Header().Get(echo.HeaderContentType); contentType != MIMEApplicationProblemJSON {
+			t.Errorf("%v: expected content type %s, got %s", tt.err, MIMEApplicationProblemJSON, contentType)
+		}
+
+		var problem models.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+
+		if problem.Type != tt.expectedType {
+			t.Errorf("%v: expected type %s, got %s", tt.err, tt.expectedType, problem.Type)
+		}
+
+		if problem.Status != tt.expectedCode || problem.Title != http.StatusText(tt.expectedCode) {
+			t.Errorf("%v: expected status %d %s, got %d %s", tt.err, tt.expectedCode, http.StatusText(tt.expectedCode), problem.Status, problem.Title)
+		}
+
+		if problem.Detail != tt.expectedDetail {
+			t.Errorf("%v: expected detail %q, got %q", tt.err, tt.expectedDetail, problem.Detail)
+		}
+
+		if problem.Instance != "/api/v1/products/1" || problem.RequestID != "req-1" {
+			t.Errorf("%v: expected instance and request ID, got %q and %q", tt.err, problem.Instance, problem.RequestID)
+		}
+	}
+}
+
+func TestHTTPErrorHandler_Validation(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	e.Validator = validators.NewValidator()
+	httpReq := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"description": "no name", "price": -1}`))
+	httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.CreateProduct(c)
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	HTTPErrorHandler(err, c)
+
+	// Assertions
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	var problem models.Problem
+	json.Unmarshal(rec.Body.Bytes(), &problem)
+
+	expected := []models.FieldError{
+		{Field: "name", Rule: "required", Message: "name is a required field"},
+		{Field: "price", Rule: "min", Param: "0", Message: "price must be 0 or greater"},
+	}
+
+	if problem.Type != "/problems/validation" {
+		t.Errorf("Expected type %s, got %s", "/problems/validation", problem.Type)
+	}
+
+	if len(problem.Errors) != len(expected) {
+		t.Fatalf("Expected %d field errors, got %v", len(expected), problem.Errors)
+	}
+
+	for i, fieldErr := range expected {
+		if problem.Errors[i] != fieldErr {
+			t.Errorf("Expected field error %+v, got %+v", fieldErr, problem.Errors[i])
+		}
+	}
+
+	if len(presenter.products) != 0 {
+		t.Error("Expected invalid product not to be created")
+	}
+}
+
+func TestHTTPErrorHandler_ValidationLocale(t *testing.T) {
+	tests := []struct {
+		acceptLanguage  string
+		expectedLocale  string
+		expectedMessage string
+	}{
+		{"", "en", "name is a required field"},
+		{"id-ID,id;q=0.9,en;q=0.8", "id", "name wajib diisi"},
+		{"fr-CH, fr;q=0.9, id;q=0.5", "id", "name wajib diisi"},
+		{"de", "en", "name is a required field"},
+		{"not a language", "en", "name is a required field"},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBufferString(`{"price": 1}`))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		httpReq.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+
+		// Test
+		err := NewProductHandler(NewSimpleProductPresenter()).CreateProduct(c)
+		if err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		HTTPErrorHandler(err, c)
+
+		// Assertions
+		var problem models.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+
+		if len(problem.Errors) != 1 || problem.Errors[0].Message != tt.expectedMessage {
+			t.Errorf("%q: expected message %q, got %v", tt.acceptLanguage, tt.expectedMessage, problem.Errors)
+		}
+
+		if locale := rec.Header().Get("Content-Language"); locale != tt.expectedLocale {
+			t.Errorf("%q: expected Content-Language %s, got %s", tt.acceptLanguage, tt.expectedLocale, locale)
+		}
+	}
+}
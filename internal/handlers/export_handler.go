@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"simple-goroutine-product/internal/presenters"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportHandler handles HTTP requests for product exports
+type ExportHandler struct {
+	presenter presenters.ExportPresenter
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(presenter presenters.ExportPresenter) *ExportHandler {
+	return &ExportHandler{
+		presenter: presenter,
+	}
+}
+
+// exportTypes maps the format query parameter to the export format and its
+// content type. The parameter doubles as the file extension of the download.
+var exportTypes = map[string]struct {
+	format      presenters.ExportFormat
+	contentType string
+}{
+	"csv":    {presenters.ExportCSV, "text/csv; charset=utf-8"},
+	"ndjson": {presenters.ExportNDJSON, "application/x-ndjson"},
+	"xlsx":   {presenters.ExportXLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+}
+
+// ExportProducts godoc
+// @Summary Export products
+// @Description Stream every product matching the list filters as CSV, NDJSON or XLSX
+// @Tags products
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv, ndjson or xlsx" default(csv)
+// @Param search query string false "Case-insensitive name substring"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param min_stock query int false "Minimum stock"
+// @Param created_from query string false "Created at or after (RFC 3339)"
+// @Param created_to query string false "Created at or before (RFC 3339)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (e.g. price,-created_at)"
+// @Success 200 {file} file
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/export [get]
+func (h *ExportHandler) ExportProducts(c echo.Context) error {
+	name := c.QueryParam("format")
+	if name == "" {
+		name = "csv"
+	}
+
+	exportType, ok := exportTypes[name]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be csv, ndjson or xlsx")
+	}
+
+	query, err := parseProductQuery(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	res := c.Response()
+	w := &exportWriter{res: res, contentType: exportType.contentType, filename: "products." + name}
+	err = h.presenter.ExportProducts(c.Request().Context(), exportType.format, query, w)
+	if err == nil {
+		// An export without products may not have written anything yet
+		w.start()
+		return nil
+	}
+	if !res.Committed {
+		return err
+	}
+
+	// The status has already been sent. Abort the connection so the client
+	// sees a failed download rather than a file that looks complete.
+	log.Println("Failed to export products:", err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportWriter sends the export's status and headers with its first write,
+// so an export that fails before writing anything is reported as an error
+type exportWriter struct {
+	res         *echo.Response
+	contentType string
+	filename    string
+}
+
+// start sends the status and headers unless they have been sent already
+func (w *exportWriter) start() {
+	if w.res.Committed {
+		return
+	}
+	w.res.Header().Set(echo.HeaderContentType, w.contentType)
+	w.res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+w.filename+`"`)
+	w.res.WriteHeader(http.StatusOK)
+}
+
+// Write sends p to the client, starting the response first
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.res.Write(p)
+}
+
+// Flush pushes written data to the client once the response has started
+func (w *exportWriter) Flush() {
+	if w.res.Committed {
+		w.res.Flush()
+	}
+}
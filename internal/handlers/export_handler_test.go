@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SimpleExportPresenter is a simple mock implementation
+type SimpleExportPresenter struct {
+	format presenters.ExportFormat
+	query  models.ProductQuery
+	// written and err, when set, replace the export with a failure after
+	// written has been sent
+	written string
+	err     error
+}
+
+func (p *SimpleExportPresenter) ExportProducts(ctx context.Context, format presenters.ExportFormat, query models.ProductQuery, w io.Writer) error {
+	p.format = format
+	p.query = query
+	if p.err != nil {
+		if p.written != "" {
+			io.WriteString(w, p.written)
+		}
+		return p.err
+	}
+	_, err := io.WriteString(w, "exported")
+	return err
+}
+
+func TestExportHandler_ExportProducts(t *testing.T) {
+	presenter := &SimpleExportPresenter{}
+	handler := NewExportHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/export?format=ndjson&min_stock=1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.ExportProducts(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if contentType := rec.Header().Get(echo.HeaderContentType); contentType != "application/x-ndjson" {
+		t.Errorf("Expected content type %s, got %s", "application/x-ndjson", contentType)
+	}
+
+	if presenter.format != presenters.ExportNDJSON {
+		t.Errorf("Expected format %d, got %d", presenters.ExportNDJSON, presenter.format)
+	}
+
+	if presenter.query.MinStock == nil || *presenter.query.MinStock != 1 {
+		t.Errorf("Expected min stock filter to be passed, got %v", presenter.query.MinStock)
+	}
+
+	if rec.Body.String() != "exported" {
+		t.Errorf("Expected body %s, got %s", "exported", rec.Body.String())
+	}
+}
+
+func TestExportHandler_ExportProductsInvalid(t *testing.T) {
+	targets := []string{
+		"/products/export?format=pdf",
+		"/products/export?sort=secret",
+	}
+
+	for _, target := range targets {
+		presenter := &SimpleExportPresenter{}
+		handler := NewExportHandler(presenter)
+
+		// Setup Echo
+		e := echo.New()
+		httpReq := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+
+		// Test
+		err := handler.ExportProducts(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestExportHandler_ExportProductsFailsBeforeRows(t *testing.T) {
+	presenter := &SimpleExportPresenter{err: errors.New("connection refused")}
+	handler := NewExportHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/export", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.ExportProducts(c)
+
+	// Errors are rendered by the central error handler
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	HTTPErrorHandler(err, c)
+
+	// Assertions
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+
+	if disposition := rec.Header().Get(echo.HeaderContentDisposition); disposition != "" {
+		t.Errorf("Expected no content disposition, got %s", disposition)
+	}
+}
+
+func TestExportHandler_ExportProductsFailsAfterRows(t *testing.T) {
+	presenter := &SimpleExportPresenter{written: "id,name\n", err: errors.New("connection reset")}
+	handler := NewExportHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/export", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	defer func() {
+		// Assertions
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("Expected the handler to abort, got %v", r)
+		}
+
+		if rec.Body.String() != "id,name\n" {
+			t.Errorf("Expected body %q, got %q", "id,name\n", rec.Body.String())
+		}
+	}()
+	handler.ExportProducts(c)
+}
+
+func TestExportHandler_ExportProductsEmpty(t *testing.T) {
+	presenter := &EmptyExportPresenter{}
+	handler := NewExportHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/export?format=ndjson", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.ExportProducts(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if contentType := rec.Header().Get(echo.HeaderContentType); contentType != "application/x-ndjson" {
+		t.Errorf("Expected content type %s, got %s", "application/x-ndjson", contentType)
+	}
+}
+
+// EmptyExportPresenter exports no products
+type EmptyExportPresenter struct{}
+
+func (p *EmptyExportPresenter) ExportProducts(ctx context.Context, format presenters.ExportFormat, query models.ProductQuery, w io.Writer) error {
+	return nil
+}
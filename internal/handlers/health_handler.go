@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"simple-goroutine-product/internal/health"
+	"simple-goroutine-product/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	checks *health.Registry
+}
+
+// NewHealthHandler creates a new health handler that reports readiness
+// using the checks in registry
+func NewHealthHandler(checks *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		checks: checks,
+	}
+}
+
+// Health godoc
+// @Summary Health check
+// @Description Legacy health check kept for existing monitors. Prefer /livez and /readyz.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /health [get]
+func (h *HealthHandler) Health(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Livez godoc
+// @Summary Liveness probe
+// @Description Report that the process is running. Dependencies are not checked.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.HealthReport{Status: models.HealthUp})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Run every dependency check concurrently and report each result. Responds 503 when any check fails.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Failure 503 {object} models.HealthReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	report := h.checks.Run(c.Request().Context())
+
+	status := http.StatusOK
+	if report.Status != models.HealthUp {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, report)
+}
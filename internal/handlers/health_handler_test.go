@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/health"
+	"simple-goroutine-product/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHealthHandler_Health(t *testing.T) {
+	handler := NewHealthHandler(health.NewRegistry(time.Second))
+
+	// Setup Echo
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Test
+	err := handler.Health(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"status":"ok"}` {
+		t.Errorf("Expected body %s, got %s", `{"status":"ok"}`, body)
+	}
+}
+
+func TestHealthHandler_Livez(t *testing.T) {
+	handler := NewHealthHandler(health.NewRegistry(time.Second))
+
+	// Setup Echo
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Test
+	err := handler.Livez(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	checks := health.NewRegistry(time.Second)
+	checks.Register("database", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	handler := NewHealthHandler(checks)
+
+	// Setup Echo
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Test
+	err := handler.Readyz(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var report models.HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if report.Checks["database"].Status != models.HealthUp {
+		t.Errorf("Expected database check %s, got %s", models.HealthUp, report.Checks["database"].Status)
+	}
+}
+
+func TestHealthHandler_ReadyzFailing(t *testing.T) {
+	checks := health.NewRegistry(time.Second)
+	checks.Register("database", health.CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+	checks.Register("schema", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	handler := NewHealthHandler(checks)
+
+	// Setup Echo
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// Test
+	err := handler.Readyz(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	var report models.HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if report.Status != models.HealthDown {
+		t.Errorf("Expected status %s, got %s", models.HealthDown, report.Status)
+	}
+
+	if report.Checks["database"].Error != "connection refused" {
+		t.Errorf("Expected database error to be reported, got %q", report.Checks["database"].Error)
+	}
+
+	if report.Checks["schema"].Status != models.HealthUp {
+		t.Errorf("Expected schema check %s, got %s", models.HealthUp, report.Checks["schema"].Status)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"simple-goroutine-product/internal/presenters"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ImportHandler handles HTTP requests for product imports
+type ImportHandler struct {
+	presenter presenters.ImportPresenter
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(presenter presenters.ImportPresenter) *ImportHandler {
+	return &ImportHandler{
+		presenter: presenter,
+	}
+}
+
+// ImportProducts godoc
+// @Summary Import products from CSV or NDJSON
+// @Description Stream a CSV (text/csv, with a header row) or NDJSON (application/x-ndjson) body. Rows with an id update that product, rows without one create a new product. Rows are validated one by one and written in batches. An import that stops part way after writing rows answers 207 with the rows written so far and the reason in aborted.
+// @Tags products
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv or ndjson, overrides Content-Type"
+// @Param dry_run query bool false "Validate rows without writing them"
+// @Success 200 {object} models.ImportResult
+// @Success 207 {object} models.ImportResult
+// @Failure 400 {object} models.Problem
+// @Failure 415 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/import [post]
+func (h *ImportHandler) ImportProducts(c echo.Context) error {
+	format, ok := importFormat(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson")
+	}
+
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid dry_run value")
+		}
+	}
+
+	result, err := h.presenter.ImportProducts(c.Request().Context(), format, c.Request().Body, dryRun, c.Validate)
+	if err != nil && result == nil {
+		return err
+	}
+
+	// Rows were written before the import stopped
+	if err != nil {
+		log.Printf("Import aborted after %d created and %d updated: %v", result.Created, result.Updated, err)
+		result.Aborted = err.Error()
+		return c.JSON(http.StatusMultiStatus, result)
+	}
+
+	if result.Failed > 0 {
+		return c.JSON(http.StatusMultiStatus, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// importFormat picks the import format from the format query parameter or
+// the request's Content-Type
+func importFormat(c echo.Context) (presenters.ImportFormat, bool) {
+	switch c.QueryParam("format") {
+	case "csv":
+		return presenters.ImportCSV, true
+	case "ndjson":
+		return presenters.ImportNDJSON, true
+	case "":
+	default:
+		return 0, false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case "text/csv":
+		return presenters.ImportCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return presenters.ImportNDJSON, true
+	default:
+		return 0, false
+	}
+}
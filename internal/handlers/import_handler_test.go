@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SimpleImportPresenter is a simple mock implementation
+type SimpleImportPresenter struct {
+	format presenters.ImportFormat
+	dryRun bool
+	body   string
+	err    error
+}
+
+func (p *SimpleImportPresenter) ImportProducts(ctx context.Context, format presenters.ImportFormat, r io.Reader, dryRun bool, validate func(interface{}) error) (*models.ImportResult, error) {
+	data, _ := io.ReadAll(r)
+	p.format = format
+	p.dryRun = dryRun
+	p.body = string(data)
+	return &models.ImportResult{DryRun: dryRun, Processed: 1, Created: 1}, p.err
+}
+
+func TestImportHandler_ImportProducts(t *testing.T) {
+	tests := []struct {
+		target         string
+		contentType    string
+		expectedCode   int
+		expectedFormat presenters.ImportFormat
+		expectedDryRun bool
+	}{
+		{"/products/import", "text/csv", http.StatusOK, presenters.ImportCSV, false},
+		{"/products/import?dry_run=true", "application/x-ndjson", http.StatusOK, presenters.ImportNDJSON, true},
+		{"/products/import?format=ndjson", "text/plain", http.StatusOK, presenters.ImportNDJSON, false},
+		{"/products/import", "application/xml", http.StatusUnsupportedMediaType, presenters.ImportCSV, false},
+		{"/products/import?dry_run=maybe", "text/csv", http.StatusBadRequest, presenters.ImportCSV, false},
+	}
+
+	for _, tt := range tests {
+		presenter := &SimpleImportPresenter{}
+		handler := NewImportHandler(presenter)
+
+		// Setup Echo
+		e := echo.New()
+		httpReq := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader("name,price\nCable,4.5\n"))
+		httpReq.Header.Set(echo.HeaderContentType, tt.contentType)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+
+		// Test
+		err := handler.ImportProducts(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%s %s: expected status code %d, got %d", tt.target, tt.contentType, tt.expectedCode, rec.Code)
+		}
+
+		if rec.Code != http.StatusOK {
+			continue
+		}
+
+		if presenter.format != tt.expectedFormat || presenter.dryRun != tt.expectedDryRun {
+			t.Errorf("%s: expected format %d and dry run %t, got %d and %t", tt.target, tt.expectedFormat, tt.expectedDryRun, presenter.format, presenter.dryRun)
+		}
+
+		if presenter.body == "" {
+			t.Errorf("%s: expected the request body to be streamed to the presenter", tt.target)
+		}
+	}
+}
+
+func TestImportHandler_ImportProductsAborted(t *testing.T) {
+	presenter := &SimpleImportPresenter{err: errors.New("connection reset")}
+	handler := NewImportHandler(presenter)
+
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodPost, "/products/import", strings.NewReader("name,price\nCable,4.5\n"))
+	httpReq.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	if err := handler.ImportProducts(c); err != nil {
+		t.Fatalf("Expected the partial result to be rendered, got %v", err)
+	}
+
+	if rec.Code != http.StatusMultiStatus {
+		t.Errorf("Expected status code %d, got %d", http.StatusMultiStatus, rec.Code)
+	}
+
+	var result models.ImportResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+
+	if result.Created != 1 || result.Aborted != "connection reset" {
+		t.Errorf("Expected the written rows and why the import stopped, got %+v", result)
+	}
+}
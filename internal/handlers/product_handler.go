@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"simple-goroutine-product/internal/models"
 	"simple-goroutine-product/internal/presenters"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -28,23 +34,26 @@ func NewProductHandler(presenter presenters.ProductPresenter) *ProductHandler {
 // @Accept json
 // @Produce json
 // @Param product body models.ProductRequest true "Product data"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of creating another product"
 // @Success 201 {object} models.ProductResponse
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /products [post]
 func (h *ProductHandler) CreateProduct(c echo.Context) error {
 	var req models.ProductRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
 	}
 
 	if err := c.Validate(req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	product, err := h.presenter.CreateProduct(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, product)
@@ -58,58 +67,174 @@ func (h *ProductHandler) CreateProduct(c echo.Context) error {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 200 {object} models.ProductResponse
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetProduct(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid product ID"})
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
 	product, err := h.presenter.GetProduct(c.Request().Context(), uint(id))
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Product not found"})
+		return err
 	}
 
+	c.Response().Header().Set("ETag", product.ETag())
 	return c.JSON(http.StatusOK, product)
 }
 
 // GetProducts godoc
 // @Summary Get all products
-// @Description Get all products with pagination
+// @Description Get all products with filtering, sorting and pagination
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Case-insensitive name substring"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param min_stock query int false "Minimum stock"
+// @Param created_from query string false "Created at or after (RFC 3339)"
+// @Param created_to query string false "Created at or before (RFC 3339)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (e.g. price,-created_at)"
+// @Param cursor query string false "Opaque cursor; when present (even empty) keyset pagination is used and page is ignored"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /products [get]
 func (h *ProductHandler) GetProducts(c echo.Context) error {
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page <= 0 {
-		page = 1
+	query, err := parseProductQuery(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 {
-		limit = 10
+	if c.QueryParams().Has("cursor") {
+		return h.getProductsByCursor(c, query)
 	}
 
-	products, total, err := h.presenter.GetProducts(c.Request().Context(), page, limit)
+	products, total, err := h.presenter.GetProducts(c.Request().Context(), query)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"data":  products,
 		"total": total,
-		"page":  page,
-		"limit": limit,
+		"page":  query.Page,
+		"limit": query.Limit,
 	})
 }
 
+// getProductsByCursor serves the product listing in keyset pagination mode
+func (h *ProductHandler) getProductsByCursor(c echo.Context, query models.ProductQuery) error {
+	if len(query.Sort) > 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "cursor pagination supports a single sort field")
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := models.DecodeCursor(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if len(query.Sort) == 1 && query.Sort[0] != cursor.Sort {
+			return echo.NewHTTPError(http.StatusBadRequest, "sort does not match cursor")
+		}
+		query.Cursor = cursor
+	}
+
+	page, err := h.presenter.GetProductsByCursor(c.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// parsePagination reads the page and limit query parameters, defaulting to
+// the first page of 10
+func parsePagination(c echo.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.QueryParam("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	return page, limit
+}
+
+// parseProductQuery builds a ProductQuery from the request's query parameters
+func parseProductQuery(c echo.Context) (models.ProductQuery, error) {
+	query := models.ProductQuery{
+		Search: strings.TrimSpace(c.QueryParam("search")),
+	}
+
+	query.Page, query.Limit = parsePagination(c)
+
+	if v := c.QueryParam("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid min_price %q", v)
+		}
+		query.MinPrice = &price
+	}
+
+	if v := c.QueryParam("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid max_price %q", v)
+		}
+		query.MaxPrice = &price
+	}
+
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return query, errors.New("min_price must not be greater than max_price")
+	}
+
+	if v := c.QueryParam("min_stock"); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil {
+			return query, fmt.Errorf("invalid min_stock %q", v)
+		}
+		query.MinStock = &stock
+	}
+
+	if v := c.QueryParam("created_from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_from %q, expected RFC 3339", v)
+		}
+		query.CreatedFrom = &from
+	}
+
+	if v := c.QueryParam("created_to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_to %q, expected RFC 3339", v)
+		}
+		query.CreatedTo = &to
+	}
+
+	if query.CreatedFrom != nil && query.CreatedTo != nil && query.CreatedFrom.After(*query.CreatedTo) {
+		return query, errors.New("created_from must not be after created_to")
+	}
+
+	sort, err := models.ParseSort(c.QueryParam("sort"))
+	if err != nil {
+		return query, err
+	}
+	query.Sort = sort
+
+	return query, nil
+}
+
 // UpdateProduct godoc
 // @Summary Update a product
 // @Description Update a product by its ID
@@ -118,57 +243,355 @@ func (h *ProductHandler) GetProducts(c echo.Context) error {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Param product body models.ProductRequest true "Updated product data"
+// @Param If-Match header string false "ETag of the version being replaced"
 // @Success 200 {object} models.ProductResponse
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /products/{id} [put]
 func (h *ProductHandler) UpdateProduct(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid product ID"})
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
 	}
 
 	var req models.ProductRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	var product *models.ProductResponse
+	err = conditionalWrite(c, func(version uint) (err error) {
+		product, err = h.presenter.UpdateProduct(c.Request().Context(), uint(id), req, version)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("ETag", product.ETag())
+	return c.JSON(http.StatusOK, product)
+}
+
+// PatchProduct godoc
+// @Summary Partially update a product
+// @Description Apply an RFC 7396 JSON Merge Patch (application/merge-patch+json) or RFC 6902 JSON Patch (application/json-patch+json) to a product
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param patch body object true "Patch document"
+// @Param If-Match header string false "ETag of the version being patched"
+// @Success 200 {object} models.ProductResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 415 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/{id} [patch]
+func (h *ProductHandler) PatchProduct(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var format presenters.PatchFormat
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case "application/merge-patch+json", echo.MIMEApplicationJSON:
+		format = presenters.MergePatch
+	case "application/json-patch+json":
+		format = presenters.JSONPatch
+	default:
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json or application/json-patch+json")
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	}
+
+	var product *models.ProductResponse
+	err = conditionalWrite(c, func(version uint) (err error) {
+		product, err = h.presenter.PatchProduct(c.Request().Context(), uint(id), format, patch, version, c.Validate)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("ETag", product.ETag())
+	return c.JSON(http.StatusOK, product)
+}
+
+// AdjustStock godoc
+// @Summary Adjust product stock
+// @Description Atomically add a signed delta to a product's stock. The stock never goes below zero.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param adjustment body models.StockAdjustmentRequest true "Stock adjustment"
+// @Success 200 {object} models.ProductResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/{id}/stock/adjust [post]
+func (h *ProductHandler) AdjustStock(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req models.StockAdjustmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
 	}
 
 	if err := c.Validate(req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	product, err := h.presenter.UpdateProduct(c.Request().Context(), uint(id), req)
+	product, err := h.presenter.AdjustStock(c.Request().Context(), uint(id), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
+	c.Response().Header().Set("ETag", product.ETag())
 	return c.JSON(http.StatusOK, product)
 }
 
 // DeleteProduct godoc
 // @Summary Delete a product
-// @Description Delete a product by its ID
+// @Description Move a product to the trash, or permanently delete it with hard=true. A hard delete also removes products already in the trash.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
+// @Param hard query bool false "Permanently delete the product and its reservations"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /products/{id} [delete]
 func (h *ProductHandler) DeleteProduct(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid product ID"})
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	hard := false
+	if v := c.QueryParam("hard"); v != "" {
+		if hard, err = strconv.ParseBool(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid hard %q, expected true or false", v))
+		}
 	}
 
-	err = h.presenter.DeleteProduct(c.Request().Context(), uint(id))
+	err = conditionalWrite(c, func(version uint) error {
+		if hard {
+			return h.presenter.PurgeProduct(c.Request().Context(), uint(id), version)
+		}
+		return h.presenter.DeleteProduct(c.Request().Context(), uint(id), version)
+	})
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Product not found"})
+		return err
 	}
 
+	if hard {
+		return c.JSON(http.StatusOK, map[string]string{"message": "Product permanently deleted"})
+	}
 	return c.JSON(http.StatusOK, map[string]string{"message": "Product deleted successfully"})
 }
+
+// GetDeletedProducts godoc
+// @Summary List deleted products
+// @Description List the products in the trash with filtering and pagination. Without a sort the most recently deleted come first.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Case-insensitive name substring"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param min_stock query int false "Minimum stock"
+// @Param created_from query string false "Created at or after (RFC 3339)"
+// @Param created_to query string false "Created at or before (RFC 3339)"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (e.g. price,-created_at)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/trash [get]
+func (h *ProductHandler) GetDeletedProducts(c echo.Context) error {
+	query, err := parseProductQuery(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	products, total, err := h.presenter.GetDeletedProducts(c.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":  products,
+		"total": total,
+		"page":  query.Page,
+		"limit": query.Limit,
+	})
+}
+
+// RestoreProduct godoc
+// @Summary Restore a deleted product
+// @Description Take a product out of the trash. Its version is incremented.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.ProductResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/{id}/restore [post]
+func (h *ProductHandler) RestoreProduct(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	product, err := h.presenter.RestoreProduct(c.Request().Context(), uint(id))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("ETag", product.ETag())
+	return c.JSON(http.StatusOK, product)
+}
+
+// GetProductHistory godoc
+// @Summary Get product history
+// @Description List the audit trail of a product, most recent change first. Every create, update, delete, restore and purge is recorded with its actor, request ID and field changes. Purged products keep their history.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/{id}/history [get]
+func (h *ProductHandler) GetProductHistory(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	page, limit := parsePagination(c)
+	entries, total, err := h.presenter.GetProductHistory(c.Request().Context(), uint(id), page, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":  entries,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// parseIfMatch parses an If-Match header into the product versions it
+// accepts. It returns nil when the header is absent or "*", which accept any
+// version. If-Match uses strong comparison, so weak tags and tags that are
+// not product versions never match and are left out. ok is false when the
+// header is malformed.
+func parseIfMatch(header string) (versions []uint, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	versions = []uint{}
+	for {
+		// Lists may contain empty elements, so stray commas are skipped
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			return versions, true
+		}
+
+		weak := strings.HasPrefix(header, "W/")
+		if weak {
+			header = header[2:]
+		}
+		if !strings.HasPrefix(header, `"`) {
+			return nil, false
+		}
+		end := strings.IndexByte(header[1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		tag := header[1 : end+1]
+		header = strings.TrimLeft(header[end+2:], " \t")
+		if header != "" && header[0] != ',' {
+			return nil, false
+		}
+
+		if version, err := strconv.ParseUint(tag, 10, 32); !weak && err == nil && version != 0 {
+			versions = append(versions, uint(version))
+		}
+	}
+}
+
+// conditionalWrite runs a write that is conditional on the product version,
+// honoring the request's If-Match header. The write is tried with each
+// version the header lists until one does not conflict; at most one of them
+// is the current version, so at most one write succeeds. Without If-Match a
+// conflict means the product changed underneath a concurrent writer and the
+// client gets 409; with it the precondition failed and the client gets 412.
+func conditionalWrite(c echo.Context, write func(version uint) error) error {
+	header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	versions, ok := parseIfMatch(header)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid If-Match header")
+	}
+
+	if versions == nil {
+		err := write(0)
+		switch {
+		case errors.Is(err, presenters.ErrVersionConflict):
+			return echo.NewHTTPError(http.StatusConflict, "Product was modified concurrently, please retry")
+		case header == "*" && errors.Is(err, presenters.ErrNotFound):
+			// "*" only matches a product that exists
+			return echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match does not match the current product version")
+		}
+		return err
+	}
+
+	for _, version := range versions {
+		if err := write(version); !errors.Is(err, presenters.ErrVersionConflict) {
+			return err
+		}
+	}
+	return echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match does not match the current product version")
+}
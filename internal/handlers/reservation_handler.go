@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReservationHandler handles HTTP requests for stock reservations
+type ReservationHandler struct {
+	presenter presenters.ReservationPresenter
+}
+
+// NewReservationHandler creates a new reservation handler
+func NewReservationHandler(presenter presenters.ReservationPresenter) *ReservationHandler {
+	return &ReservationHandler{
+		presenter: presenter,
+	}
+}
+
+// CreateReservation godoc
+// @Summary Hold product stock
+// @Description Reserve stock of a product for a limited time while checkout completes
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param reservation body models.ReservationRequest true "Reservation data"
+// @Success 201 {object} models.ReservationResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /products/{id}/reservations [post]
+func (h *ReservationHandler) CreateReservation(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID")
+	}
+
+	var req models.ReservationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	reservation, err := h.presenter.CreateReservation(c.Request().Context(), uint(id), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, reservation)
+}
+
+// GetReservation godoc
+// @Summary Get a reservation by ID
+// @Description Get a stock reservation by its ID
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} models.ReservationResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /reservations/{id} [get]
+func (h *ReservationHandler) GetReservation(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := h.presenter.GetReservation(c.Request().Context(), uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, reservation)
+}
+
+// ConfirmReservation godoc
+// @Summary Confirm a reservation
+// @Description Turn an active hold into a permanent stock decrement
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} models.ReservationResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /reservations/{id}/confirm [post]
+func (h *ReservationHandler) ConfirmReservation(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := h.presenter.ConfirmReservation(c.Request().Context(), uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, reservation)
+}
+
+// ReleaseReservation godoc
+// @Summary Release a reservation
+// @Description Give the stock held by an active reservation back
+// @Tags reservations
+// @Accept json
+// @Produce json
+// @Param id path int true "Reservation ID"
+// @Success 200 {object} models.ReservationResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /reservations/{id}/release [post]
+func (h *ReservationHandler) ReleaseReservation(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := h.presenter.ReleaseReservation(c.Request().Context(), uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, reservation)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"simple-goroutine-product/internal/validators"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SimpleReservationPresenter is a simple mock implementation
+type SimpleReservationPresenter struct {
+	stock        map[uint]int
+	reservations []models.ReservationResponse
+}
+
+func NewSimpleReservationPresenter(stock map[uint]int) *SimpleReservationPresenter {
+	return &SimpleReservationPresenter{stock: stock}
+}
+
+func (p *SimpleReservationPresenter) CreateReservation(ctx context.Context, productID uint, req models.ReservationRequest) (*models.ReservationResponse, error) {
+	if p.stock[productID] < req.Quantity {
+		return nil, presenters.ErrInsufficientStock
+	}
+	p.stock[productID] -= req.Quantity
+	reservation := models.ReservationResponse{
+		ID:        uint(len(p.reservations) + 1),
+		ProductID: productID,
+		Quantity:  req.Quantity,
+		Status:    models.ReservationActive,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	p.reservations = append(p.reservations, reservation)
+	return &reservation, nil
+}
+
+func (p *SimpleReservationPresenter) GetReservation(ctx context.Context, id uint) (*models.ReservationResponse, error) {
+	for _, reservation := range p.reservations {
+		if reservation.ID == id {
+			return &reservation, nil
+		}
+	}
+	return nil, presenters.ErrNotFound
+}
+
+func (p *SimpleReservationPresenter) ConfirmReservation(ctx context.Context, id uint) (*models.ReservationResponse, error) {
+	return p.transition(id, models.ReservationConfirmed)
+}
+
+func (p *SimpleReservationPresenter) ReleaseReservation(ctx context.Context, id uint) (*models.ReservationResponse, error) {
+	return p.transition(id, models.ReservationReleased)
+}
+
+func (p *SimpleReservationPresenter) ExpireReservations(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (p *SimpleReservationPresenter) RunExpiry(ctx context.Context, interval time.Duration) {}
+
+func (p *SimpleReservationPresenter) Wait(ctx context.Context) error {
+	return nil
+}
+
+func (p *SimpleReservationPresenter) InFlight() int {
+	return 0
+}
+
+func (p *SimpleReservationPresenter) transition(id uint, status models.ReservationStatus) (*models.ReservationResponse, error) {
+	for i, reservation := range p.reservations {
+		if reservation.ID == id {
+			if reservation.Status != models.ReservationActive {
+				return nil, presenters.ErrReservationNotActive
+			}
+			p.reservations[i].Status = status
+			return &p.reservations[i], nil
+		}
+	}
+	return nil, presenters.ErrNotFound
+}
+
+func TestReservationHandler_CreateReservation(t *testing.T) {
+	presenter := NewSimpleReservationPresenter(map[uint]int{1: 5})
+	handler := NewReservationHandler(presenter)
+
+	tests := []struct {
+		body         string
+		expectedCode int
+	}{
+		{`{"quantity": 3}`, http.StatusCreated},
+		{`{"quantity": 3}`, http.StatusConflict},
+		{`{"quantity": 0}`, http.StatusBadRequest},
+		{`{"quantity": 1, "ttl_seconds": 7200}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(http.MethodPost, "/products/1/reservations", bytes.NewBufferString(tt.body))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Test
+		err := handler.CreateReservation(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%s: expected status code %d, got %d", tt.body, tt.expectedCode, rec.Code)
+		}
+	}
+}
+
+func TestReservationHandler_ConfirmReservation(t *testing.T) {
+	presenter := NewSimpleReservationPresenter(map[uint]int{1: 5})
+	handler := NewReservationHandler(presenter)
+
+	ctx := context.Background()
+	_, err := presenter.CreateReservation(ctx, 1, models.ReservationRequest{Quantity: 2})
+	if err != nil {
+		t.Fatalf("Failed to create reservation: %v", err)
+	}
+
+	expectedCodes := []int{http.StatusOK, http.StatusConflict}
+	for _, expectedCode := range expectedCodes {
+		// Setup Echo
+		e := echo.New()
+		httpReq := httptest.NewRequest(http.MethodPost, "/reservations/1/confirm", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Test
+		err = handler.ConfirmReservation(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != expectedCode {
+			t.Errorf("Expected status code %d, got %d", expectedCode, rec.Code)
+		}
+	}
+
+	if presenter.reservations[0].Status != models.ReservationConfirmed {
+		t.Errorf("Expected status %s, got %s", models.ReservationConfirmed, presenter.reservations[0].Status)
+	}
+}
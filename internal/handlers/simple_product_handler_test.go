@@ -4,19 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"simple-goroutine-product/internal/metrics"
 	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/presenters"
+	"simple-goroutine-product/internal/repositories"
 	"simple-goroutine-product/internal/validators"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 // SimpleProductPresenter is a simple mock implementation
 type SimpleProductPresenter struct {
-	products []models.ProductResponse
-	nextID   uint
+	products  []models.ProductResponse
+	deleted   []models.ProductResponse
+	nextID    uint
+	lastQuery models.ProductQuery
+	lastPatch presenters.PatchFormat
 }
 
 func NewSimpleProductPresenter() *SimpleProductPresenter {
@@ -33,6 +41,7 @@ func (p *SimpleProductPresenter) CreateProduct(ctx context.Context, req models.P
 		Description: req.Description,
 		Price:       req.Price,
 		Stock:       req.Stock,
+		Version:     1,
 	}
 	p.products = append(p.products, *product)
 	p.nextID++
@@ -45,16 +54,26 @@ func (p *SimpleProductPresenter) GetProduct(ctx context.Context, id uint) (*mode
 			return &product, nil
 		}
 	}
-	return nil, nil
+	return nil, presenters.ErrNotFound
 }
 
-func (p *SimpleProductPresenter) GetProducts(ctx context.Context, page, limit int) ([]models.ProductResponse, int64, error) {
+func (p *SimpleProductPresenter) GetProducts(ctx context.Context, query models.ProductQuery) ([]models.ProductResponse, int64, error) {
+	p.lastQuery = query
 	return p.products, int64(len(p.products)), nil
 }
 
-func (p *SimpleProductPresenter) UpdateProduct(ctx context.Context, id uint, req models.ProductRequest) (*models.ProductResponse, error) {
+func (p *SimpleProductPresenter) GetProductsByCursor(ctx context.Context, query models.ProductQuery) (*models.ProductCursorPage, error) {
+	p.lastQuery = query
+	return &models.ProductCursorPage{Data: p.products, Limit: query.Limit}, nil
+}
+
+func (p *SimpleProductPresenter) UpdateProduct(ctx context.Context, id uint, req models.ProductRequest, version uint) (*models.ProductResponse, error) {
 	for i, product := range p.products {
 		if product.ID == id {
+			if version != 0 && product.Version != version {
+				return nil, presenters.ErrVersionConflict
+			}
+			p.products[i].Version++
 			p.products[i].Name = req.Name
 			p.products[i].Description = req.Description
 			p.products[i].Price = req.Price
@@ -62,19 +81,122 @@ func (p *SimpleProductPresenter) UpdateProduct(ctx context.Context, id uint, req
 			return &p.products[i], nil
 		}
 	}
-	return nil, nil
+	return nil, presenters.ErrNotFound
+}
+
+func (p *SimpleProductPresenter) PatchProduct(ctx context.Context, id uint, format presenters.PatchFormat, patch []byte, version uint, validate func(interface{}) error) (*models.ProductResponse, error) {
+	p.lastPatch = format
+	for i, product := range p.products {
+		if product.ID == id {
+			if version != 0 && product.Version != version {
+				return nil, presenters.ErrVersionConflict
+			}
+			return &p.products[i], nil
+		}
+	}
+	return nil, presenters.ErrNotFound
 }
 
-func (p *SimpleProductPresenter) DeleteProduct(ctx context.Context, id uint) error {
+func (p *SimpleProductPresenter) AdjustStock(ctx context.Context, id uint, req models.StockAdjustmentRequest) (*models.ProductResponse, error) {
 	for i, product := range p.products {
 		if product.ID == id {
+			if product.Stock+req.Delta < 0 {
+				return nil, presenters.ErrInsufficientStock
+			}
+			p.products[i].Stock += req.Delta
+			p.products[i].Version++
+			return &p.products[i], nil
+		}
+	}
+	return nil, presenters.ErrNotFound
+}
+
+func (p *SimpleProductPresenter) DeleteProduct(ctx context.Context, id uint, version uint) error {
+	for i, product := range p.products {
+		if product.ID == id {
+			if version != 0 && product.Version != version {
+				return presenters.ErrVersionConflict
+			}
+			deletedAt := time.Now()
+			product.DeletedAt = &deletedAt
+			p.deleted = append(p.deleted, product)
 			p.products = append(p.products[:i], p.products[i+1:]...)
 			return nil
 		}
 	}
+	return presenters.ErrNotFound
+}
+
+func (p *SimpleProductPresenter) GetDeletedProducts(ctx context.Context, query models.ProductQuery) ([]models.ProductResponse, int64, error) {
+	p.lastQuery = query
+	return p.deleted, int64(len(p.deleted)), nil
+}
+
+func (p *SimpleProductPresenter) RestoreProduct(ctx context.Context, id uint) (*models.ProductResponse, error) {
+	for i, product := range p.deleted {
+		if product.ID == id {
+			product.DeletedAt = nil
+			product.Version++
+			p.products = append(p.products, product)
+			p.deleted = append(p.deleted[:i], p.deleted[i+1:]...)
+			return &product, nil
+		}
+	}
+	if _, err := p.GetProduct(ctx, id); err == nil {
+		return nil, presenters.ErrProductNotDeleted
+	}
+	return nil, presenters.ErrNotFound
+}
+
+func (p *SimpleProductPresenter) PurgeProduct(ctx context.Context, id uint, version uint) error {
+	if err := p.DeleteProduct(ctx, id, version); err != nil && !errors.Is(err, presenters.ErrNotFound) {
+		return err
+	}
+	for i, product := range p.deleted {
+		if product.ID == id {
+			if version != 0 && product.Version != version {
+				return presenters.ErrVersionConflict
+			}
+			p.deleted = append(p.deleted[:i], p.deleted[i+1:]...)
+			return nil
+		}
+	}
+	return presenters.ErrNotFound
+}
+
+func (p *SimpleProductPresenter) PurgeDeletedProducts(ctx context.Context, olderThan time.Duration) (int64, error) {
+	purged := int64(len(p.deleted))
+	p.deleted = nil
+	return purged, nil
+}
+
+func (p *SimpleProductPresenter) GetProductHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditResponse, int64, error) {
+	p.lastQuery = models.ProductQuery{Page: page, Limit: limit}
+	product, err := p.GetProduct(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	entry := models.ProductAuditResponse{
+		ID:        1,
+		ProductID: product.ID,
+		Action:    models.AuditCreate,
+		Actor:     "alice",
+		Changes:   []models.FieldChange{{Field: "name", After: product.Name}},
+		Version:   1,
+	}
+	return []models.ProductAuditResponse{entry}, 1, nil
+}
+
+func (p *SimpleProductPresenter) RunPurge(ctx context.Context, interval, olderThan time.Duration) {}
+
+func (p *SimpleProductPresenter) Wait(ctx context.Context) error {
 	return nil
 }
 
+func (p *SimpleProductPresenter) InFlight() int {
+	return 0
+}
+
 func TestSimpleProductHandler_CreateProduct(t *testing.T) {
 	presenter := NewSimpleProductPresenter()
 	handler := NewProductHandler(presenter)
@@ -167,6 +289,10 @@ func TestSimpleProductHandler_GetProduct(t *testing.T) {
 	if response.Name != created.Name {
 		t.Errorf("Expected name %s, got %s", created.Name, response.Name)
 	}
+
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("Expected ETag %s, got %s", `"1"`, etag)
+	}
 }
 
 func TestSimpleProductHandler_UpdateProduct(t *testing.T) {
@@ -278,11 +404,623 @@ func TestSimpleProductHandler_DeleteProduct(t *testing.T) {
 
 	// Verify product is deleted
 	result, err := presenter.GetProduct(ctx, uint(1))
-	if err != nil {
-		t.Errorf("Expected no error when getting deleted product, got %v", err)
+	if !errors.Is(err, presenters.ErrNotFound) {
+		t.Errorf("Expected not found error when getting deleted product, got %v", err)
 	}
 
 	if result != nil {
 		t.Error("Expected nil result for deleted product")
 	}
 }
+
+func TestSimpleProductHandler_GetProducts(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products?search=phone&max_price=50&min_stock=1&sort=price,-created_at&limit=5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.GetProducts(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	query := presenter.lastQuery
+	if query.Page != 1 || query.Limit != 5 {
+		t.Errorf("Expected page 1 and limit 5, got %d and %d", query.Page, query.Limit)
+	}
+
+	if query.Search != "phone" {
+		t.Errorf("Expected search %s, got %s", "phone", query.Search)
+	}
+
+	if query.MaxPrice == nil || *query.MaxPrice != 50 {
+		t.Errorf("Expected max price 50, got %v", query.MaxPrice)
+	}
+
+	if query.MinStock == nil || *query.MinStock != 1 {
+		t.Errorf("Expected min stock 1, got %v", query.MinStock)
+	}
+
+	expectedSort := []models.SortField{{Field: "price"}, {Field: "created_at", Desc: true}}
+	if len(query.Sort) != len(expectedSort) || query.Sort[0] != expectedSort[0] || query.Sort[1] != expectedSort[1] {
+		t.Errorf("Expected sort %v, got %v", expectedSort, query.Sort)
+	}
+}
+
+func TestSimpleProductHandler_GetProductsInvalidQuery(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	targets := []string{
+		"/products?sort=password",
+		"/products?sort=price,-price",
+		"/products?min_price=abc",
+		"/products?min_price=10&max_price=5",
+		"/products?created_from=yesterday",
+		"/products?cursor=not-a-cursor",
+		"/products?cursor=&sort=price,name",
+	}
+
+	for _, target := range targets {
+		// Setup Echo
+		e := echo.New()
+		httpReq := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+
+		// Test
+		err := handler.GetProducts(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestSimpleProductHandler_GetProductsByCursor(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	sort := models.SortField{Field: "price", Desc: true}
+	cursor := models.NewCursor(sort, &models.Product{ID: 4, Price: 12.5}, false)
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products?limit=20&cursor="+cursor.Encode(), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	// Test
+	err := handler.GetProducts(c)
+
+	// Assertions
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	query := presenter.lastQuery
+	if query.Cursor == nil {
+		t.Fatal("Expected cursor to be passed to presenter")
+	}
+
+	if query.Cursor.Sort != sort || query.Cursor.ID != 4 || query.Cursor.Value != 12.5 {
+		t.Errorf("Expected cursor %v, got %v", cursor, *query.Cursor)
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+
+	if _, ok := response["total"]; ok {
+		t.Error("Expected no total in cursor mode")
+	}
+}
+
+func TestSimpleProductHandler_UpdateProductIfMatch(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:  "Original Product",
+		Price: 50.00,
+	}
+
+	ctx := context.Background()
+	_, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	updateReq := models.ProductRequest{
+		Name:  "Updated Product",
+		Price: 149.99,
+	}
+	reqBody, _ := json.Marshal(updateReq)
+
+	// Each successful update bumps the version
+	tests := []struct {
+		id           string
+		ifMatch      string
+		expectedCode int
+	}{
+		{"1", `"1"`, http.StatusOK},
+		{"1", `"1"`, http.StatusPreconditionFailed},
+		{"1", `W/"2"`, http.StatusPreconditionFailed},
+		{"1", `"2"`, http.StatusOK},
+		{"1", `"1", "3", "7"`, http.StatusOK},
+		{"1", `"1", "2", "3"`, http.StatusPreconditionFailed},
+		{"1", `W/"4", "9"`, http.StatusPreconditionFailed},
+		{"1", `"abc", W/"3", "4"`, http.StatusOK},
+		{"1", `*`, http.StatusOK},
+		{"1", `, "6",`, http.StatusOK},
+		{"1", `"7`, http.StatusBadRequest},
+		{"1", `"7" "8"`, http.StatusBadRequest},
+		{"1", `7`, http.StatusBadRequest},
+		{"2", `*`, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(http.MethodPut, "/products/"+tt.id, bytes.NewBuffer(reqBody))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		httpReq.Header.Set("If-Match", tt.ifMatch)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(tt.id)
+
+		// Test
+		err = handler.UpdateProduct(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("Product %s, If-Match %s: expected status code %d, got %d", tt.id, tt.ifMatch, tt.expectedCode, rec.Code)
+		}
+	}
+
+	if presenter.products[0].Version != 7 {
+		t.Errorf("Expected version 7, got %d", presenter.products[0].Version)
+	}
+}
+
+func TestSimpleProductHandler_DeleteProductIfMatch(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:  "Test Product",
+		Price: 99.99,
+	}
+
+	ctx := context.Background()
+	_, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	// Setup Echo
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+	httpReq.Header.Set("If-Match", `"5"`)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	// Test
+	err = handler.DeleteProduct(c)
+
+	// Errors are rendered by the central error handler
+	if err != nil {
+		HTTPErrorHandler(err, c)
+	}
+
+	// Assertions
+
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, got %d", http.StatusPreconditionFailed, rec.Code)
+	}
+
+	if len(presenter.products) != 1 {
+		t.Error("Expected product to survive a failed conditional delete")
+	}
+}
+
+func TestSimpleProductHandler_PatchProduct(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:  "Test Product",
+		Price: 99.99,
+	}
+
+	ctx := context.Background()
+	_, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	tests := []struct {
+		contentType    string
+		expectedCode   int
+		expectedFormat presenters.PatchFormat
+	}{
+		{"application/merge-patch+json", http.StatusOK, presenters.MergePatch},
+		{"application/json-patch+json", http.StatusOK, presenters.JSONPatch},
+		{"application/json; charset=UTF-8", http.StatusOK, presenters.MergePatch},
+		{"text/plain", http.StatusUnsupportedMediaType, presenters.MergePatch},
+	}
+
+	for _, tt := range tests {
+		presenter.lastPatch = presenters.MergePatch
+
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewBufferString(`{"stock": 3}`))
+		httpReq.Header.Set(echo.HeaderContentType, tt.contentType)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Test
+		err = handler.PatchProduct(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%s: expected status code %d, got %d", tt.contentType, tt.expectedCode, rec.Code)
+		}
+
+		if presenter.lastPatch != tt.expectedFormat {
+			t.Errorf("%s: expected patch format %d, got %d", tt.contentType, tt.expectedFormat, presenter.lastPatch)
+		}
+	}
+}
+
+func TestProductHandler_PatchProductInvalidResult(t *testing.T) {
+	repo := repositories.NewMemoryProductRepository(repositories.NewMemoryStore())
+	presenter := presenters.NewProductPresenter(repo, time.Second, metrics.Nop{})
+	handler := NewProductHandler(presenter)
+
+	ctx := context.Background()
+	if _, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 99.99}); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	// Setup Echo
+	e := echo.New()
+	e.Validator = validators.NewValidator()
+	httpReq := httptest.NewRequest(http.MethodPatch, "/products/1", bytes.NewBufferString(`{"price": -1}`))
+	httpReq.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	// Test
+	err := handler.PatchProduct(c)
+
+	// Errors are rendered by the central error handler
+	if err != nil {
+		HTTPErrorHandler(err, c)
+	}
+
+	// Assertions
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	var problem models.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "price" {
+		t.Errorf("Expected a price field error, got %v", problem.Errors)
+	}
+}
+
+func TestSimpleProductHandler_AdjustStock(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:  "Test Product",
+		Price: 99.99,
+		Stock: 5,
+	}
+
+	ctx := context.Background()
+	_, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	tests := []struct {
+		body          string
+		expectedCode  int
+		expectedStock int
+	}{
+		{`{"delta": -3, "reason": "order #1001"}`, http.StatusOK, 2},
+		{`{"delta": -3, "reason": "order #1002"}`, http.StatusConflict, 2},
+		{`{"delta": 10}`, http.StatusBadRequest, 2},
+		{`{"delta": 0, "reason": "noop"}`, http.StatusBadRequest, 2},
+		{`{"delta": 10, "reason": "restock"}`, http.StatusOK, 12},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(http.MethodPost, "/products/1/stock/adjust", bytes.NewBufferString(tt.body))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Test
+		err = handler.AdjustStock(c)
+
+		// Errors are rendered by the central error handler
+		if err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		// Assertions
+
+		if rec.Code != tt.expectedCode {
+			t.Errorf("%s: expected status code %d, got %d", tt.body, tt.expectedCode, rec.Code)
+		}
+
+		if presenter.products[0].Stock != tt.expectedStock {
+			t.Errorf("%s: expected stock %d, got %d", tt.body, tt.expectedStock, presenter.products[0].Stock)
+		}
+	}
+}
+
+func TestSimpleProductHandler_NotFound(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	tests := []struct {
+		method string
+		call   func(c echo.Context) error
+	}{
+		{http.MethodGet, handler.GetProduct},
+		{http.MethodPut, handler.UpdateProduct},
+		{http.MethodDelete, handler.DeleteProduct},
+	}
+
+	for _, tt := range tests {
+		// Setup Echo
+		e := echo.New()
+		e.Validator = validators.NewValidator()
+		httpReq := httptest.NewRequest(tt.method, "/products/42", bytes.NewBufferString(`{"name": "Ghost", "price": 1}`))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("42")
+
+		// Test
+		err := tt.call(c)
+		if err == nil {
+			t.Fatalf("%s: expected an error for a missing product", tt.method)
+		}
+		HTTPErrorHandler(err, c)
+
+		// Assertions
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status code %d, got %d", tt.method, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func TestSimpleProductHandler_TrashAndRestore(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	ctx := context.Background()
+	if _, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 99.99}); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if err := presenter.DeleteProduct(ctx, 1, 0); err != nil {
+		t.Fatalf("Failed to delete product: %v", err)
+	}
+
+	// The trash lists the deleted product
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/trash?search=test&page=1&limit=5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+
+	if err := handler.GetDeletedProducts(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var page struct {
+		Data  []models.ProductResponse `json:"data"`
+		Total int64                    `json:"total"`
+		Limit int                      `json:"limit"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &page)
+
+	if page.Total != 1 || len(page.Data) != 1 || page.Data[0].DeletedAt == nil {
+		t.Errorf("Expected the deleted product with deleted_at, got %s", rec.Body.String())
+	}
+	if page.Limit != 5 || presenter.lastQuery.Search != "test" {
+		t.Errorf("Expected the query to be parsed, got %+v", presenter.lastQuery)
+	}
+
+	// Restoring brings it back with a new version
+	httpReq = httptest.NewRequest(http.MethodPost, "/products/1/restore", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httpReq, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	if err := handler.RestoreProduct(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("Expected ETag \"2\", got %s", etag)
+	}
+
+	// Restoring a live product conflicts
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httpReq, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	if err := handler.RestoreProduct(c); err != nil {
+		HTTPErrorHandler(err, c)
+	}
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestSimpleProductHandler_HardDeleteProduct(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	ctx := context.Background()
+	if _, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 99.99}); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	e := echo.New()
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/products/1?hard=maybe", http.StatusBadRequest},
+		{"/products/1?hard=true", http.StatusOK},
+		{"/products/1?hard=true", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		httpReq := httptest.NewRequest(http.MethodDelete, tt.target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httpReq, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		if err := handler.DeleteProduct(c); err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.target, tt.code, rec.Code)
+		}
+	}
+
+	if len(presenter.products) != 0 || len(presenter.deleted) != 0 {
+		t.Error("Expected a hard delete to skip the trash")
+	}
+}
+
+func TestSimpleProductHandler_GetProductHistory(t *testing.T) {
+	presenter := NewSimpleProductPresenter()
+	handler := NewProductHandler(presenter)
+
+	if _, err := presenter.CreateProduct(context.Background(), models.ProductRequest{Name: "Test Product", Price: 99.99}); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	e := echo.New()
+	httpReq := httptest.NewRequest(http.MethodGet, "/products/1/history?page=2&limit=5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httpReq, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	if err := handler.GetProductHistory(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var page struct {
+		Data  []models.ProductAuditResponse `json:"data"`
+		Total int64                         `json:"total"`
+		Page  int                           `json:"page"`
+		Limit int                           `json:"limit"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &page)
+
+	if page.Total != 1 || len(page.Data) != 1 || page.Data[0].Action != models.AuditCreate {
+		t.Errorf("Expected the create entry, got %s", rec.Body.String())
+	}
+	if page.Page != 2 || page.Limit != 5 || presenter.lastQuery.Page != 2 || presenter.lastQuery.Limit != 5 {
+		t.Errorf("Expected page 2 of 5, got %+v", presenter.lastQuery)
+	}
+
+	// Unknown products have no history
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"abc", http.StatusBadRequest},
+		{"999", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/products/"+tt.id+"/history", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(tt.id)
+
+		if err := handler.GetProductHistory(c); err != nil {
+			HTTPErrorHandler(err, c)
+		}
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.id, tt.code, rec.Code)
+		}
+	}
+}
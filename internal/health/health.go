@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"simple-goroutine-product/internal/models"
+	"sync"
+	"time"
+)
+
+// Checker checks that a dependency of the service is usable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Registry holds the readiness checks of the service
+type Registry struct {
+	mu      sync.RWMutex
+	names   []string
+	checks  map[string]Checker
+	timeout time.Duration
+}
+
+// NewRegistry creates an empty registry. Each check is given timeout to
+// finish before it is reported as down.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		checks:  make(map[string]Checker),
+		timeout: timeout,
+	}
+}
+
+// Register adds a named check, replacing any check with the same name
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.checks[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.checks[name] = checker
+}
+
+// Run runs every check concurrently and reports the service as up only if
+// all of them pass
+func (r *Registry) Run(ctx context.Context) models.HealthReport {
+	r.mu.RLock()
+	names := append([]string(nil), r.names...)
+	checks := make([]Checker, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]models.HealthCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, checker := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	report := models.HealthReport{Status: models.HealthUp, Checks: make(map[string]models.HealthCheck, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != models.HealthUp {
+			report.Status = models.HealthDown
+		}
+	}
+	return report
+}
+
+// run runs a single check, giving up on it once the timeout passes even if
+// the checker ignores its context
+func (r *Registry) run(ctx context.Context, checker Checker) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = fmt.Errorf("check did not finish: %w", ctx.Err())
+	}
+
+	result := models.HealthCheck{Status: models.HealthUp, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = models.HealthDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Saturation returns a check that fails once inUse reaches limit
+func Saturation(inUse func() int, limit int) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if n := inUse(); n >= limit {
+			return fmt.Errorf("saturated: %d of %d in use", n, limit)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"simple-goroutine-product/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_AllUp(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.Register("schema", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, models.HealthUp, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, models.HealthUp, report.Checks["database"].Status)
+	assert.Empty(t, report.Checks["database"].Error)
+}
+
+func TestRegistry_OneDown(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+	registry.Register("schema", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, models.HealthDown, report.Status)
+	assert.Equal(t, models.HealthDown, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, models.HealthUp, report.Checks["schema"].Status)
+}
+
+func TestRegistry_RunsConcurrently(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	for _, name := range []string{"a", "b", "c"} {
+		registry.Register(name, CheckerFunc(func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}))
+	}
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, models.HealthUp, report.Status)
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	// Ignores its context, so the registry has to stop waiting on its own
+	registry.Register("stuck", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, models.HealthDown, report.Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "check did not finish")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRegistry_RegisterReplaces(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, models.HealthUp, report.Status)
+	assert.Len(t, report.Checks, 1)
+}
+
+func TestSaturation(t *testing.T) {
+	inUse := 9
+	checker := Saturation(func() int { return inUse }, 10)
+
+	assert.NoError(t, checker.Check(context.Background()))
+
+	inUse = 10
+	assert.EqualError(t, checker.Check(context.Background()), "saturated: 10 of 10 in use")
+}
@@ -0,0 +1,103 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHook returns a hook that appends its events to events
+func recordingHook(name string, events *[]string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			*events = append(*events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestLifecycle_StartStopOrder(t *testing.T) {
+	var events []string
+	app := New()
+	app.Append(recordingHook("database", &events, nil, nil))
+	app.Append(Hook{Name: "no hooks"})
+	app.Append(recordingHook("server", &events, nil, nil))
+
+	ctx := context.Background()
+	assert.NoError(t, app.Start(ctx))
+	assert.NoError(t, app.Stop(ctx))
+
+	assert.Equal(t, []string{"start database", "start server", "stop server", "stop database"}, events)
+}
+
+func TestLifecycle_StartFailureStopsStarted(t *testing.T) {
+	var events []string
+	app := New()
+	app.Append(recordingHook("database", &events, nil, nil))
+	app.Append(recordingHook("server", &events, errors.New("port in use"), nil))
+	app.Append(recordingHook("never", &events, nil, nil))
+
+	ctx := context.Background()
+	err := app.Start(ctx)
+
+	assert.EqualError(t, err, "start server: port in use")
+	assert.Equal(t, []string{"start database", "start server", "stop database"}, events)
+
+	// Nothing is left to stop
+	assert.NoError(t, app.Stop(ctx))
+	assert.Len(t, events, 3)
+}
+
+func TestLifecycle_StopRunsEveryHook(t *testing.T) {
+	var events []string
+	app := New()
+	app.Append(recordingHook("database", &events, nil, errors.New("close failed")))
+	app.Append(recordingHook("server", &events, nil, errors.New("drain timed out")))
+
+	ctx := context.Background()
+	assert.NoError(t, app.Start(ctx))
+	err := app.Stop(ctx)
+
+	assert.ErrorContains(t, err, "stop server: drain timed out")
+	assert.ErrorContains(t, err, "stop database: close failed")
+	assert.Equal(t, []string{"start database", "start server", "stop server", "stop database"}, events)
+}
+
+func TestBackground(t *testing.T) {
+	stopped := false
+	app := New()
+	app.Append(Background("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped = true
+	}))
+
+	ctx := context.Background()
+	assert.NoError(t, app.Start(ctx))
+	assert.NoError(t, app.Stop(ctx))
+	assert.True(t, stopped)
+}
+
+func TestBackground_StopDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	app := New()
+	app.Append(Background("stuck", func(ctx context.Context) {
+		<-release
+	}))
+
+	assert.NoError(t, app.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, app.Stop(ctx), context.DeadlineExceeded)
+}
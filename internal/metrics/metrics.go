@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Outcome is how a presenter operation ended
+type Outcome string
+
+const (
+	// OutcomeSuccess means the operation completed without error
+	OutcomeSuccess Outcome = "success"
+	// OutcomeError means the operation completed with an error
+	OutcomeError Outcome = "error"
+	// OutcomeTimeout means the presenter stopped waiting after its timeout
+	OutcomeTimeout Outcome = "timeout"
+	// OutcomeCanceled means the caller's context was cancelled first
+	OutcomeCanceled Outcome = "canceled"
+)
+
+// Recorder receives the measurements taken by the application. The
+// Prometheus implementation is used in production; tests can substitute
+// their own to assert on what was recorded.
+type Recorder interface {
+	// ObserveRequest records a handled HTTP request. route is the route
+	// pattern, such as /api/v1/products/:id.
+	ObserveRequest(method, route string, status int, duration time.Duration)
+	// ObserveOperation records a presenter operation and how it ended
+	ObserveOperation(presenter, operation string, outcome Outcome, duration time.Duration)
+	// AddInFlight changes the number of running presenter goroutines
+	AddInFlight(presenter string, delta int)
+	// ObserveQuery records a database statement
+	ObserveQuery(operation, table string, duration time.Duration, failed bool)
+}
+
+// Nop is a Recorder that discards every measurement
+type Nop struct{}
+
+// ObserveRequest does nothing
+func (Nop) ObserveRequest(method, route string, status int, duration time.Duration) {}
+
+// ObserveOperation does nothing
+func (Nop) ObserveOperation(presenter, operation string, outcome Outcome, duration time.Duration) {}
+
+// AddInFlight does nothing
+func (Nop) AddInFlight(presenter string, delta int) {}
+
+// ObserveQuery does nothing
+func (Nop) ObserveQuery(operation, table string, duration time.Duration, failed bool) {}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestPrometheus_Handler(t *testing.T) {
+	p := NewPrometheus()
+	p.ObserveRequest("GET", "/api/v1/products/:id", 200, 15*time.Millisecond)
+	p.ObserveOperation("product", "create", OutcomeTimeout, 30*time.Second)
+	p.AddInFlight("product", 2)
+	p.ObserveQuery("query", "products", time.Millisecond, true)
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `product_api_http_request_duration_seconds_count{method="GET",route="/api/v1/products/:id",status="200"} 1`)
+	assert.Contains(t, string(body), `product_api_presenter_operations_total{operation="create",outcome="timeout",presenter="product"} 1`)
+	assert.Contains(t, string(body), `product_api_presenter_goroutines_in_flight{presenter="product"} 2`)
+	assert.Contains(t, string(body), `product_api_db_query_errors_total{operation="query",table="products"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestPrometheus_InFlight(t *testing.T) {
+	p := NewPrometheus()
+	p.AddInFlight("reservation", 1)
+	p.AddInFlight("reservation", 1)
+	p.AddInFlight("reservation", -1)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.inFlight.WithLabelValues("reservation")))
+}
+
+// recordingQueries is a Recorder that keeps query measurements
+type recordingQueries struct {
+	Nop
+	queries []string
+}
+
+func (r *recordingQueries) ObserveQuery(operation, table string, duration time.Duration, failed bool) {
+	r.queries = append(r.queries, operation+" "+table)
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGORMPlugin(t *testing.T) {
+	// Dry run builds statements and runs the callbacks without a server
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	recorder := &recordingQueries{}
+	require.NoError(t, db.Use(NewGORMPlugin(recorder)))
+
+	db.Create(&widget{Name: "a"})
+	db.Find(&[]widget{})
+	db.Model(&widget{ID: 1}).Update("name", "b")
+	db.Delete(&widget{ID: 1})
+
+	assert.Equal(t, []string{"create widgets", "query widgets", "update widgets", "delete widgets"}, recorder.queries)
+}
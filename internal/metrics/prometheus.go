@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "product_api"
+
+// Prometheus is a Recorder that exposes its measurements in the Prometheus
+// text format
+type Prometheus struct {
+	registry          *prometheus.Registry
+	requestDuration   *prometheus.HistogramVec
+	operationDuration *prometheus.HistogramVec
+	operations        *prometheus.CounterVec
+	inFlight          *prometheus.GaugeVec
+	queryDuration     *prometheus.HistogramVec
+	queryErrors       *prometheus.CounterVec
+}
+
+// NewPrometheus creates a Prometheus recorder with its own registry, which
+// also collects Go runtime and process metrics
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "presenter_operation_duration_seconds",
+			Help:      "Time callers waited for presenter operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"presenter", "operation"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "presenter_operations_total",
+			Help:      "Presenter operations by outcome: success, error, timeout or canceled.",
+		}, []string{"presenter", "operation", "outcome"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "presenter_goroutines_in_flight",
+			Help:      "Presenter goroutines currently running.",
+		}, []string{"presenter"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of database statements by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Database statements that failed, by operation and table.",
+		}, []string{"operation", "table"}),
+	}
+
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.requestDuration,
+		p.operationDuration,
+		p.operations,
+		p.inFlight,
+		p.queryDuration,
+		p.queryErrors,
+	)
+	return p
+}
+
+// Handler serves the metrics for scraping
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled HTTP request
+func (p *Prometheus) ObserveRequest(method, route string, status int, duration time.Duration) {
+	p.requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveOperation records a presenter operation and how it ended
+func (p *Prometheus) ObserveOperation(presenter, operation string, outcome Outcome, duration time.Duration) {
+	p.operationDuration.WithLabelValues(presenter, operation).Observe(duration.Seconds())
+	p.operations.WithLabelValues(presenter, operation, string(outcome)).Inc()
+}
+
+// AddInFlight changes the number of running presenter goroutines
+func (p *Prometheus) AddInFlight(presenter string, delta int) {
+	p.inFlight.WithLabelValues(presenter).Add(float64(delta))
+}
+
+// ObserveQuery records a database statement
+func (p *Prometheus) ObserveQuery(operation, table string, duration time.Duration, failed bool) {
+	p.queryDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+	if failed {
+		p.queryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"simple-goroutine-product/internal/audit"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ActorHeader names who is making the request in the audit trail
+	ActorHeader = "X-Actor"
+	// anonymousActor is recorded for requests without an ActorHeader
+	anonymousActor = "anonymous"
+	// maxActorLength matches the size of the audit entries' actor column
+	maxActorLength = 255
+)
+
+// Actor stores who is making the request and its request ID in the request
+// context, so the changes it makes are attributed in the audit trail. It
+// must run after middleware.RequestID.
+func Actor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			name := strings.TrimSpace(req.Header.Get(ActorHeader))
+			if name == "" {
+				name = anonymousActor
+			}
+			if len(name) > maxActorLength {
+				name = strings.ToValidUTF8(name[:maxActorLength], "")
+			}
+
+			requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+			if requestID == "" {
+				requestID = req.Header.Get(echo.HeaderXRequestID)
+			}
+
+			ctx := audit.WithActor(req.Context(), audit.Actor{Name: name, RequestID: requestID})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
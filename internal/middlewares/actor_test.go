@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/audit"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// serveActor returns the actor the handler sees for a request with headers
+func serveActor(headers map[string]string) audit.Actor {
+	var actor audit.Actor
+	e := echo.New()
+	e.Use(middleware.RequestID())
+	e.Use(Actor())
+	e.GET("/products", func(c echo.Context) error {
+		actor = audit.ActorFrom(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	return actor
+}
+
+func TestActor(t *testing.T) {
+	actor := serveActor(map[string]string{ActorHeader: " alice ", echo.HeaderXRequestID: "req-1"})
+
+	if actor.Name != "alice" {
+		t.Errorf("Expected actor alice, got %q", actor.Name)
+	}
+	if actor.RequestID != "req-1" {
+		t.Errorf("Expected request ID req-1, got %q", actor.RequestID)
+	}
+}
+
+func TestActor_Defaults(t *testing.T) {
+	actor := serveActor(nil)
+
+	if actor.Name != "anonymous" {
+		t.Errorf("Expected actor anonymous, got %q", actor.Name)
+	}
+	if actor.RequestID == "" {
+		t.Errorf("Expected the generated request ID to be recorded")
+	}
+}
+
+func TestActor_TruncatesLongNames(t *testing.T) {
+	actor := serveActor(map[string]string{ActorHeader: strings.Repeat("a", 300)})
+
+	if len(actor.Name) != 255 {
+		t.Errorf("Expected the actor to be truncated to 255 bytes, got %d", len(actor.Name))
+	}
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"simple-goroutine-product/internal/audit"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderIdempotencyKey is the request header carrying the idempotency key
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marks responses replayed from the store
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength is the longest key the store accepts
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes requests that carry an Idempotency-Key header safe to
+// retry. The first request with a key runs normally and its response is
+// stored for ttl; repeats of that request get the stored response back
+// without running the handler again. Reusing a key for a different request
+// is rejected with 422, and a repeat that arrives while the first request
+// is still running gets 409. Server errors are not stored so they can be
+// retried.
+//
+// Keys are namespaced by the route and by the X-Actor header or client IP,
+// so clients that happen to pick the same key do not collide. Neither is
+// authenticated and both can be forged, so they do not keep responses
+// apart: a stored response is replayed to any request with the same key,
+// method, URL, content type and body. Clients should use unguessable keys.
+func Idempotency(store repositories.IdempotencyRepository, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			record := &models.IdempotencyRecord{
+				Key:         scopeKey(c, key),
+				RequestHash: requestHash(c.Request(), body),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+
+			ctx := c.Request().Context()
+			existing, err := store.Reserve(ctx, record)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return replay(c, existing, record.RequestHash)
+			}
+
+			// Give the key up unless a response is stored, also when the
+			// handler panics, so the request can be retried. The client may
+			// be gone by now, which must not stop the write.
+			ctx = context.WithoutCancel(ctx)
+			stored := false
+			defer func() {
+				if stored {
+					return
+				}
+				if err := store.Release(ctx, record.Key); err != nil {
+					log.Println("Failed to release idempotency key:", err)
+				}
+			}()
+
+			// Keep a copy of everything the handler writes
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			if c.Response().Status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			record.StatusCode = c.Response().Status
+			record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+			record.Body = recorder.body.Bytes()
+			if err := store.Complete(ctx, record); err != nil {
+				log.Println("Failed to store idempotent response:", err)
+				return nil
+			}
+			stored = true
+			return nil
+		}
+	}
+}
+
+// replay answers a repeated request from the stored record
+func replay(c echo.Context, record *models.IdempotencyRecord, hash string) error {
+	if record.RequestHash != hash {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	}
+	if !record.Completed() {
+		return echo.NewHTTPError(http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+	}
+
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(record.StatusCode, record.ContentType, record.Body)
+}
+
+// scopeKey namespaces an Idempotency-Key by the client that claims to have
+// sent it and the method and path it was sent to. Clients are told apart by
+// their X-Actor, falling back to their IP address for anonymous requests.
+func scopeKey(c echo.Context, key string) string {
+	client := "ip:" + c.RealIP()
+	if actor := audit.ActorFrom(c.Request().Context()).Name; actor != anonymousActor && actor != audit.System {
+		client = "actor:" + actor
+	}
+
+	hash := sha256.New()
+	io.WriteString(hash, client+"\n"+c.Request().Method+" "+c.Request().URL.Path+"\n"+key)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// requestHash fingerprints a request by its method, URL, content type and
+// body
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n"+req.Header.Get(echo.HeaderContentType)+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// RunIdempotencyExpiry deletes expired idempotency records every interval
+// until ctx is cancelled
+func RunIdempotencyExpiry(ctx context.Context, store repositories.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := store.DeleteExpired(ctx, time.Now()); err != nil {
+				log.Println("Failed to delete expired idempotency keys:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// responseRecorder copies the response body while writing it through
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write records and writes part of the response body
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
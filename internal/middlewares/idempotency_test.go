@@ -0,0 +1,229 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// newIdempotentServer serves POST /products through the idempotency
+// middleware and counts how often the handler runs
+func newIdempotentServer(store repositories.IdempotencyRepository, ttl time.Duration, status int) (*echo.Echo, *int) {
+	calls := 0
+	e := echo.New()
+	e.POST("/products", func(c echo.Context) error {
+		calls++
+		if status >= http.StatusInternalServerError {
+			return echo.NewHTTPError(status, "database unavailable")
+		}
+		return c.JSON(status, map[string]int{"id": calls})
+	}, Idempotency(store, ttl))
+	return e, &calls
+}
+
+func sendIdempotent(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusCreated)
+
+	first := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+	second := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+
+	if *calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", *calls)
+	}
+
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replay of %d %s, got %d %s", first.Code, first.Body.String(), second.Code, second.Body.String())
+	}
+
+	if second.Header().Get(HeaderIdempotentReplayed) != "true" {
+		t.Error("Expected replayed response to be marked")
+	}
+
+	if contentType := second.Header().Get(echo.HeaderContentType); contentType != first.Header().Get(echo.HeaderContentType) {
+		t.Errorf("Expected content type %s, got %s", first.Header().Get(echo.HeaderContentType), contentType)
+	}
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusCreated)
+
+	sendIdempotent(e, "", `{"name": "Cable"}`)
+	sendIdempotent(e, "", `{"name": "Cable"}`)
+
+	if *calls != 2 {
+		t.Errorf("Expected handler to run twice, ran %d times", *calls)
+	}
+}
+
+func TestIdempotency_DifferentPayload(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusCreated)
+
+	sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+	rec := sendIdempotent(e, "key-1", `{"name": "Charger"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	if *calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", *calls)
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	store := repositories.NewMemoryIdempotencyRepository()
+	e, calls := newIdempotentServer(store, time.Hour, http.StatusCreated)
+
+	// Claim the key the way a concurrent request would
+	req := httptest.NewRequest(http.MethodPost, "/products", nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	store.Reserve(context.Background(), &models.IdempotencyRecord{
+		Key:         scopeKey(echo.New().NewContext(req, nil), "key-1"),
+		RequestHash: requestHash(req, []byte(`{"name": "Cable"}`)),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+
+	rec := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	if *calls != 0 {
+		t.Errorf("Expected handler not to run, ran %d times", *calls)
+	}
+}
+
+func TestIdempotency_Expiry(t *testing.T) {
+	store := repositories.NewMemoryIdempotencyRepository()
+	e, calls := newIdempotentServer(store, time.Millisecond, http.StatusCreated)
+
+	sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+	time.Sleep(5 * time.Millisecond)
+	rec := sendIdempotent(e, "key-1", `{"name": "Charger"}`)
+
+	if rec.Code != http.StatusCreated || *calls != 2 {
+		t.Errorf("Expected expired key to be reusable, got %d after %d calls", rec.Code, *calls)
+	}
+
+	deleted, _ := store.DeleteExpired(context.Background(), time.Now().Add(time.Second))
+	if deleted != 1 {
+		t.Errorf("Expected 1 expired key to be deleted, got %d", deleted)
+	}
+}
+
+func TestIdempotency_ServerErrorNotStored(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusServiceUnavailable)
+
+	for i := 1; i <= 2; i++ {
+		rec := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+
+		if *calls != i {
+			t.Errorf("Expected handler to run %d times, ran %d times", i, *calls)
+		}
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	calls := 0
+	e := echo.New()
+	e.Use(middleware.Recover())
+	e.POST("/products", func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			panic("handler bug")
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+	}, Idempotency(repositories.NewMemoryIdempotencyRepository(), time.Hour))
+
+	first := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+	second := sendIdempotent(e, "key-1", `{"name": "Cable"}`)
+
+	if first.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the panic to be recovered as %d, got %d", http.StatusInternalServerError, first.Code)
+	}
+
+	if second.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("Expected the retry to run, got %d after %d calls", second.Code, calls)
+	}
+}
+
+func TestIdempotency_ScopedByClient(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusCreated)
+	e.Use(Actor())
+
+	send := func(actor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name": "Cable"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		req.Header.Set(ActorHeader, actor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	alice := send("alice")
+	bob := send("bob")
+	again := send("alice")
+
+	if *calls != 2 {
+		t.Errorf("Expected handler to run once per client, ran %d times", *calls)
+	}
+
+	if bob.Header().Get(HeaderIdempotentReplayed) != "" || bob.Body.String() == alice.Body.String() {
+		t.Errorf("Expected bob not to get alice's response, got %s", bob.Body.String())
+	}
+
+	if again.Header().Get(HeaderIdempotentReplayed) != "true" || again.Body.String() != alice.Body.String() {
+		t.Errorf("Expected alice's retry to be replayed, got %s", again.Body.String())
+	}
+}
+
+func TestIdempotency_ForgedActorNeedsSameRequest(t *testing.T) {
+	e, calls := newIdempotentServer(repositories.NewMemoryIdempotencyRepository(), time.Hour, http.StatusCreated)
+	e.Use(Actor())
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		req.Header.Set(ActorHeader, "alice")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	send(`{"name": "Cable"}`)
+	forged := send(`{"name": "Other"}`)
+
+	if forged.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, forged.Code)
+	}
+
+	if *calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", *calls)
+	}
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"simple-goroutine-product/internal/metrics"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that did not match any route, so unknown
+// paths cannot create unbounded label values
+const unmatchedRoute = "unmatched"
+
+// Metrics records the duration of every request with recorder, labelled by
+// method, route pattern and response status
+func Metrics(recorder metrics.Recorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			// Render the error now so the status it maps to is known
+			if err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			recorder.ObserveRequest(c.Request().Method, route, c.Response().Status, time.Since(start))
+			return err
+		}
+	}
+}
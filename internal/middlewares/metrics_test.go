@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"simple-goroutine-product/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RecordingMetrics is a metrics.Recorder that keeps request measurements
+type RecordingMetrics struct {
+	metrics.Nop
+	requests []string
+}
+
+func (m *RecordingMetrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.requests = append(m.requests, method+" "+route+" "+http.StatusText(status))
+}
+
+func TestMetrics_Routes(t *testing.T) {
+	recorder := &RecordingMetrics{}
+	e := echo.New()
+	e.Use(Metrics(recorder))
+	e.GET("/products/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/products/1", "/products/0", "/unknown/path"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expected := []string{
+		"GET /products/:id OK",
+		"GET /products/:id Not Found",
+		"GET unmatched Not Found",
+	}
+	if len(recorder.requests) != len(expected) {
+		t.Fatalf("Expected %d requests recorded, got %v", len(expected), recorder.requests)
+	}
+	for i := range expected {
+		if recorder.requests[i] != expected[i] {
+			t.Errorf("Expected request %d to be recorded as %q, got %q", i, expected[i], recorder.requests[i])
+		}
+	}
+}
+
+func TestMetrics_ErrorRenderedOnce(t *testing.T) {
+	recorder := &RecordingMetrics{}
+	e := echo.New()
+	e.Use(Metrics(recorder))
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusConflict, "conflict")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	if body := rec.Body.String(); body != "{\"message\":\"conflict\"}\n" {
+		t.Errorf("Expected the error to be rendered once, got %q", body)
+	}
+}
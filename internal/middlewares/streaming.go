@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Streaming lifts the server's read and write timeouts for a route, so that
+// uploads and downloads that legitimately take longer than them are not cut
+// off mid-stream. Writers that cannot change their deadlines are left as
+// they are.
+func Streaming() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			controller := http.NewResponseController(c.Response())
+			_ = controller.SetReadDeadline(time.Time{})
+			_ = controller.SetWriteDeadline(time.Time{})
+			return next(c)
+		}
+	}
+}
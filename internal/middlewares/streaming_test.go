@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStreaming(t *testing.T) {
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return c.String(http.StatusOK, "exported")
+	}
+	e.GET("/export", handler, Streaming())
+	e.GET("/other", handler)
+
+	server := httptest.NewUnstartedServer(e)
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/export")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "exported" {
+		t.Errorf("Expected body %s, got %q", "exported", body)
+	}
+
+	// Routes without the middleware keep the server's timeout
+	if res, err := http.Get(server.URL + "/other"); err == nil {
+		res.Body.Close()
+		t.Error("Expected the write timeout to cut the response off")
+	}
+}
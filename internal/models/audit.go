@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// AuditAction is the kind of change an audit entry records
+type AuditAction string
+
+const (
+	// AuditCreate records a new product
+	AuditCreate AuditAction = "create"
+	// AuditUpdate records a change to a product's fields
+	AuditUpdate AuditAction = "update"
+	// AuditDelete records a product moved to the trash
+	AuditDelete AuditAction = "delete"
+	// AuditRestore records a product taken out of the trash
+	AuditRestore AuditAction = "restore"
+	// AuditPurge records a product deleted for good
+	AuditPurge AuditAction = "purge"
+)
+
+// FieldChange is the value of one product field before and after a change.
+// Before is nil for a created product.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// FieldChanges is stored as a JSON array
+type FieldChanges []FieldChange
+
+// Value encodes the changes for the database
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		c = FieldChanges{}
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Scan decodes the changes read from the database
+func (c *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return errors.New("unsupported type for field changes")
+	}
+}
+
+// ProductAuditEntry records one change to a product: who made it, in which
+// request and how each field changed. Entries outlive the product so that
+// purged products keep their history.
+type ProductAuditEntry struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	ProductID uint         `json:"product_id" gorm:"not null;index:idx_product_audit_entries_product"`
+	Action    AuditAction  `json:"action" gorm:"not null;size:16"`
+	Actor     string       `json:"actor" gorm:"not null;size:255"`
+	RequestID string       `json:"request_id" gorm:"size:255"`
+	Changes   FieldChanges `json:"changes" gorm:"not null"`
+	// Reason is the caller's explanation for a stock adjustment
+	Reason string `json:"reason" gorm:"size:255"`
+	// Version is the product version the change produced
+	Version   uint      `json:"version" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName overrides the table name used by ProductAuditEntry
+func (ProductAuditEntry) TableName() string {
+	return "product_audit_entries"
+}
+
+// ProductAuditResponse represents an entry of a product's history
+type ProductAuditResponse struct {
+	ID        uint          `json:"id"`
+	ProductID uint          `json:"product_id"`
+	Action    AuditAction   `json:"action"`
+	Actor     string        `json:"actor"`
+	RequestID string        `json:"request_id,omitempty"`
+	Changes   []FieldChange `json:"changes"`
+	Reason    string        `json:"reason,omitempty"`
+	Version   uint          `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// ToResponse converts ProductAuditEntry to ProductAuditResponse
+func (e *ProductAuditEntry) ToResponse() ProductAuditResponse {
+	changes := e.Changes
+	if changes == nil {
+		changes = FieldChanges{}
+	}
+	return ProductAuditResponse{
+		ID:        e.ID,
+		ProductID: e.ProductID,
+		Action:    e.Action,
+		Actor:     e.Actor,
+		RequestID: e.RequestID,
+		Changes:   changes,
+		Reason:    e.Reason,
+		Version:   e.Version,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// DiffProducts lists the audited fields that differ between two versions
+// of a product. A nil before lists every field, as for a created product.
+func DiffProducts(before, after *Product) []FieldChange {
+	fields := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{name: "name", after: after.Name},
+		{name: "description", after: after.Description},
+		{name: "price", after: after.Price},
+		{name: "stock", after: after.Stock},
+	}
+	if before != nil {
+		fields[0].before = before.Name
+		fields[1].before = before.Description
+		fields[2].before = before.Price
+		fields[3].before = before.Stock
+	}
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if before == nil || field.before != field.after {
+			changes = append(changes, FieldChange{Field: field.name, Before: field.before, After: field.after})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,64 @@
+package models
+
+// MaxBulkOperations limits the number of operations in a single bulk request
+const MaxBulkOperations = 5000
+
+// BulkOperationType identifies what a bulk operation does
+type BulkOperationType string
+
+const (
+	// BulkCreate creates a new product
+	BulkCreate BulkOperationType = "create"
+	// BulkUpdate replaces an existing product
+	BulkUpdate BulkOperationType = "update"
+	// BulkDelete deletes an existing product
+	BulkDelete BulkOperationType = "delete"
+)
+
+// BulkStatus describes the outcome of a single bulk operation
+type BulkStatus string
+
+const (
+	// BulkStatusCreated marks a product that was created
+	BulkStatusCreated BulkStatus = "created"
+	// BulkStatusUpdated marks a product that was updated
+	BulkStatusUpdated BulkStatus = "updated"
+	// BulkStatusDeleted marks a product that was deleted
+	BulkStatusDeleted BulkStatus = "deleted"
+	// BulkStatusFailed marks an operation that failed on its own
+	BulkStatusFailed BulkStatus = "failed"
+	// BulkStatusRolledBack marks an operation undone because another
+	// operation in the same atomic batch failed
+	BulkStatusRolledBack BulkStatus = "rolled_back"
+)
+
+// BulkOperation represents a single create, update or delete in a bulk request
+type BulkOperation struct {
+	Op      BulkOperationType `json:"op" validate:"required,oneof=create update delete"`
+	ID      uint              `json:"id" validate:"required_unless=Op create"`
+	Version uint              `json:"version"`
+	Product *ProductRequest   `json:"product" validate:"required_unless=Op delete"`
+}
+
+// BulkRequest represents the request payload for bulk product operations.
+// Atomic requests run in a single transaction and are rolled back entirely
+// if any operation fails; otherwise each operation succeeds or fails alone.
+type BulkRequest struct {
+	Atomic     bool            `json:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkResult represents the outcome of the operation at Index
+type BulkResult struct {
+	Index  int        `json:"index"`
+	Status BulkStatus `json:"status"`
+	ID     uint       `json:"id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// BulkResponse represents the response payload for bulk product operations
+type BulkResponse struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// HealthStatus is the state of the service or one of its dependencies
+type HealthStatus string
+
+const (
+	// HealthUp means the check passed
+	HealthUp HealthStatus = "up"
+	// HealthDown means the check failed or did not finish in time
+	HealthDown HealthStatus = "down"
+)
+
+// HealthReport represents the response payload for liveness and readiness
+type HealthReport struct {
+	Status HealthStatus           `json:"status" example:"up"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the result of a single readiness check
+type HealthCheck struct {
+	Status   HealthStatus `json:"status" example:"up"`
+	Duration string       `json:"duration" example:"1.204ms"`
+	Error    string       `json:"error,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord stores the response to a request sent with an
+// Idempotency-Key so that retries of the request can be replayed. A record
+// with a zero StatusCode belongs to a request that is still in progress.
+type IdempotencyRecord struct {
+	Key         string    `json:"key" gorm:"primaryKey;size:255"`
+	RequestHash string    `json:"request_hash" gorm:"not null;size:64"`
+	StatusCode  int       `json:"status_code" gorm:"not null;default:0"`
+	ContentType string    `json:"content_type" gorm:"size:255"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name used by IdempotencyRecord
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the request owning the record has finished
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,20 @@
+package models
+
+// Problem represents an RFC 7807 problem details error response
+type Problem struct {
+	Type      string       `json:"type" example:"/problems/not-found"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"product not found"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/products/42"`
+	RequestID string       `json:"request_id,omitempty" example:"3f2c1e9a7b5d4c8e"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Rule    string `json:"rule" example:"min"`
+	Param   string `json:"param,omitempty" example:"0"`
+	Message string `json:"message" example:"price must be 0 or greater"`
+}
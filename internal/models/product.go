@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,9 +14,14 @@ type Product struct {
 	Description string         `json:"description"`
 	Price       float64        `json:"price" gorm:"not null" validate:"required,min=0"`
 	Stock       int            `json:"stock" gorm:"default:0" validate:"min=0"`
+	Version     uint           `json:"version" gorm:"not null;default:1"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Reserved is the quantity held by active reservations. It is computed
+	// when the product is loaded and never stored.
+	Reserved int `json:"-" gorm:"-"`
 }
 
 // ProductRequest represents the request payload for creating/updating products
@@ -26,26 +32,54 @@ type ProductRequest struct {
 	Stock       int     `json:"stock" validate:"min=0"`
 }
 
+// StockAdjustmentRequest represents the request payload for adjusting stock
+type StockAdjustmentRequest struct {
+	Delta  int    `json:"delta" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
 // ProductResponse represents the response payload for products
 type ProductResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Price          float64   `json:"price"`
+	Stock          int       `json:"stock"`
+	AvailableStock int       `json:"available_stock"`
+	Version        uint      `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// DeletedAt is only set for products in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ToResponse converts Product to ProductResponse
 func (p *Product) ToResponse() ProductResponse {
-	return ProductResponse{
-		ID:          p.ID,
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
-		Stock:       p.Stock,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+	response := ProductResponse{
+		ID:             p.ID,
+		Name:           p.Name,
+		Description:    p.Description,
+		Price:          p.Price,
+		Stock:          p.Stock,
+		AvailableStock: p.AvailableStock(),
+		Version:        p.Version,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if p.DeletedAt.Valid {
+		deletedAt := p.DeletedAt.Time
+		response.DeletedAt = &deletedAt
 	}
+	return response
+}
+
+// AvailableStock returns the stock that is not held by active reservations.
+// It is only negative for stock oversold before holds were enforced.
+func (p *Product) AvailableStock() int {
+	return p.Stock - p.Reserved
+}
+
+// ETag returns the entity tag identifying the current version of the product
+func (r ProductResponse) ETag() string {
+	return fmt.Sprintf("\"%d\"", r.Version)
 }
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset paginated product listing. It records
+// the sort key and id of the boundary row and the direction to page in.
+type Cursor struct {
+	Sort     SortField
+	Value    interface{}
+	ID       uint
+	Backward bool
+}
+
+// cursorPayload is the wire format of a Cursor before base64 encoding
+type cursorPayload struct {
+	Field    string `json:"f"`
+	Desc     bool   `json:"d,omitempty"`
+	Value    string `json:"v"`
+	ID       uint   `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// ProductCursorPage represents a page of products in cursor pagination mode
+type ProductCursorPage struct {
+	Data       []ProductResponse `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+	Limit      int               `json:"limit"`
+}
+
+// NewCursor creates a cursor positioned at the given product
+func NewCursor(sort SortField, p *Product, backward bool) Cursor {
+	return Cursor{
+		Sort:     sort,
+		Value:    p.SortValue(sort.Field),
+		ID:       p.ID,
+		Backward: backward,
+	}
+}
+
+// Encode returns the opaque string representation of the cursor
+func (c Cursor) Encode() string {
+	payload := cursorPayload{
+		Field:    c.Sort.Field,
+		Desc:     c.Sort.Desc,
+		Value:    formatCursorValue(c.Value),
+		ID:       c.ID,
+		Backward: c.Backward,
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, ok := ProductSortColumns[payload.Field]; !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	value, err := parseCursorValue(payload.Field, payload.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{
+		Sort:     SortField{Field: payload.Field, Desc: payload.Desc},
+		Value:    value,
+		ID:       payload.ID,
+		Backward: payload.Backward,
+	}, nil
+}
+
+// KeysetSort returns the single sort field used for cursor pagination.
+// The cursor's own sort takes precedence, then the first requested sort
+// field, then ascending id.
+func (q ProductQuery) KeysetSort() SortField {
+	if q.Cursor != nil {
+		return q.Cursor.Sort
+	}
+	if len(q.Sort) > 0 {
+		return q.Sort[0]
+	}
+	return SortField{Field: "id"}
+}
+
+// SortValue returns the value of one of the ProductSortColumns fields of
+// the product
+func (p *Product) SortValue(field string) interface{} {
+	switch field {
+	case "name":
+		return p.Name
+	case "price":
+		return p.Price
+	case "stock":
+		return p.Stock
+	case "created_at":
+		return p.CreatedAt
+	case "updated_at":
+		return p.UpdatedAt
+	default:
+		return p.ID
+	}
+}
+
+// formatCursorValue renders a sort key value as a string
+func formatCursorValue(v interface{}) string {
+	switch value := v.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parseCursorValue converts a formatted sort key back to its Go type
+func parseCursorValue(field, raw string) (interface{}, error) {
+	switch field {
+	case "name":
+		return raw, nil
+	case "price":
+		return strconv.ParseFloat(raw, 64)
+	case "stock":
+		return strconv.Atoi(raw)
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, raw)
+	default:
+		id, err := strconv.ParseUint(raw, 10, 64)
+		return uint(id), err
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+// MaxImportErrors limits how many row errors an import reports in detail
+const MaxImportErrors = 1000
+
+// ProductImportRow represents a single row of a product import. Rows with
+// an ID update that product, rows without one create a new product.
+type ProductImportRow struct {
+	ID uint `json:"id"`
+	ProductRequest
+}
+
+// ImportError represents a row that could not be imported
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult represents the response payload for product imports
+type ImportResult struct {
+	DryRun    bool          `json:"dry_run"`
+	Processed int           `json:"processed"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Failed    int           `json:"failed"`
+	Errors    []ImportError `json:"errors"`
+	// Aborted is why the import stopped part way. The rows counted as
+	// created or updated were written; the rest of the stream was not read.
+	Aborted string `json:"aborted,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProductSortColumns whitelists the fields products can be sorted by,
+// mapping the public field name to its database column
+var ProductSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"price":      "price",
+	"stock":      "stock",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// SortField represents a single ordering clause
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ProductQuery represents the filtering, sorting and pagination options for listing products
+type ProductQuery struct {
+	Page        int
+	Limit       int
+	Search      string
+	MinPrice    *float64
+	MaxPrice    *float64
+	MinStock    *int
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        []SortField
+	Cursor      *Cursor
+}
+
+// ParseSort parses a comma separated sort expression such as "price,-created_at".
+// A leading "-" sorts the field in descending order. Fields that are not in
+// ProductSortColumns are rejected.
+func ParseSort(expr string) ([]SortField, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+
+		if _, ok := ProductSortColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate sort field %q", name)
+		}
+		seen[name] = true
+
+		fields = append(fields, SortField{Field: name, Desc: desc})
+	}
+
+	return fields, nil
+}
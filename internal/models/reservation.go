@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// ReservationStatus represents the lifecycle state of a stock hold
+type ReservationStatus string
+
+const (
+	// ReservationActive holds stock until it is confirmed, released or expires
+	ReservationActive ReservationStatus = "active"
+	// ReservationConfirmed has been turned into a stock decrement
+	ReservationConfirmed ReservationStatus = "confirmed"
+	// ReservationReleased was given back before it expired
+	ReservationReleased ReservationStatus = "released"
+	// ReservationExpired passed its expiry time while still active
+	ReservationExpired ReservationStatus = "expired"
+)
+
+// Reservation represents a temporary hold on product stock
+type Reservation struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	ProductID uint              `json:"product_id" gorm:"not null;index:idx_reservations_product_status"`
+	Quantity  int               `json:"quantity" gorm:"not null"`
+	Status    ReservationStatus `json:"status" gorm:"not null;size:16;index:idx_reservations_product_status;index:idx_reservations_status_expires"`
+	ExpiresAt time.Time         `json:"expires_at" gorm:"not null;index:idx_reservations_status_expires"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ReservationRequest represents the request payload for holding stock
+type ReservationRequest struct {
+	Quantity   int `json:"quantity" validate:"required,min=1"`
+	TTLSeconds int `json:"ttl_seconds" validate:"omitempty,min=1,max=3600"`
+}
+
+// ReservationResponse represents the response payload for reservations
+type ReservationResponse struct {
+	ID        uint              `json:"id"`
+	ProductID uint              `json:"product_id"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ToResponse converts Reservation to ReservationResponse
+func (r *Reservation) ToResponse() ReservationResponse {
+	return ReservationResponse{
+		ID:        r.ID,
+		ProductID: r.ProductID,
+		Quantity:  r.Quantity,
+		Status:    r.Status,
+		ExpiresAt: r.ExpiresAt,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
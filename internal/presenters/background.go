@@ -0,0 +1,91 @@
+package presenters
+
+import (
+	"context"
+	"errors"
+	"simple-goroutine-product/internal/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// background tracks the goroutines a presenter starts for writes. A write
+// is cancelled when its request times out or is cancelled, but may take a
+// moment to stop, so shutdown waits for them before closing the database.
+type background struct {
+	wg       sync.WaitGroup
+	inFlight atomic.Int64
+	name     string
+	metrics  metrics.Recorder
+}
+
+// start starts a presenter operation. The returned context is cancelled
+// when the operation returns, which aborts any query still running for it,
+// for example in a goroutine whose result the caller stopped waiting for.
+// The returned function ends the operation's span and records its latency
+// and outcome, and must be deferred with the operation's error result.
+func (b *background) start(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	begin := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	ctx, endSpan := startSpan(ctx, b.name+"."+operation)
+	return ctx, func(err *error) {
+		cancel()
+		b.observe(operation, begin, *err)
+		endSpan(err)
+	}
+}
+
+// spawn runs fn in a tracked goroutine with its own span. fn's context is
+// cancelled along with ctx, so fn must pass it to the repository to stop
+// when the caller gives up.
+func (b *background) spawn(ctx context.Context, operation string, fn func(ctx context.Context)) {
+	ctx, span := tracer.Start(ctx, b.name+"."+operation+".goroutine")
+
+	b.wg.Add(1)
+	b.inFlight.Add(1)
+	b.metrics.AddInFlight(b.name, 1)
+	go func() {
+		defer b.wg.Done()
+		defer b.metrics.AddInFlight(b.name, -1)
+		defer b.inFlight.Add(-1)
+		defer span.End()
+		fn(ctx)
+	}()
+}
+
+// observe records how long the caller waited for an operation and how it
+// ended
+func (b *background) observe(operation string, begin time.Time, err error) {
+	outcome := metrics.OutcomeSuccess
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrTimeout):
+		outcome = metrics.OutcomeTimeout
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		outcome = metrics.OutcomeCanceled
+	default:
+		outcome = metrics.OutcomeError
+	}
+	b.metrics.ObserveOperation(b.name, operation, outcome, time.Since(begin))
+}
+
+// InFlight returns the number of tracked goroutines still running
+func (b *background) InFlight() int {
+	return int(b.inFlight.Load())
+}
+
+// Wait blocks until every tracked goroutine has finished or ctx is done
+func (b *background) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
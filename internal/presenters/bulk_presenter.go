@@ -0,0 +1,217 @@
+package presenters
+
+import (
+	"context"
+	"errors"
+	"log"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"sync"
+)
+
+// BulkPresenter interface for bulk product operations
+type BulkPresenter interface {
+	ProcessBulk(ctx context.Context, req models.BulkRequest, validate func(interface{}) error) (*models.BulkResponse, error)
+}
+
+// errRolledBack marks operations undone because another operation in the
+// same atomic batch failed
+var errRolledBack = errors.New("rolled back because another operation failed")
+
+// bulkPresenter implements BulkPresenter
+type bulkPresenter struct {
+	background
+	productRepo repositories.ProductRepository
+	workers     int
+}
+
+// NewBulkPresenter creates a new bulk presenter that processes best-effort
+// batches with the given number of worker goroutines. Every batch and
+// worker is recorded with recorder.
+func NewBulkPresenter(productRepo repositories.ProductRepository, workers int, recorder metrics.Recorder) BulkPresenter {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &bulkPresenter{
+		background:  background{name: "bulk", metrics: recorder},
+		productRepo: productRepo,
+		workers:     workers,
+	}
+}
+
+// ProcessBulk validates and applies every operation in the request. Best-effort
+// batches are fanned out across the worker pool; atomic batches run in order
+// inside a single transaction.
+func (p *bulkPresenter) ProcessBulk(ctx context.Context, req models.BulkRequest, validate func(interface{}) error) (_ *models.BulkResponse, err error) {
+	ctx, end := p.start(ctx, "process")
+	defer end(&err)
+
+	results := make([]models.BulkResult, len(req.Operations))
+	valid := true
+	for i, op := range req.Operations {
+		results[i].Index = i
+		if err := validate(op); err != nil {
+			results[i].Status = models.BulkStatusFailed
+			results[i].Error = publicError(err)
+			valid = false
+		}
+	}
+
+	if req.Atomic {
+		if valid {
+			p.processAtomic(ctx, req.Operations, results)
+		} else {
+			rollBack(results)
+		}
+	} else {
+		p.processConcurrently(ctx, req.Operations, results)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response := &models.BulkResponse{Results: results}
+	for _, result := range results {
+		if result.Status == models.BulkStatusFailed || result.Status == models.BulkStatusRolledBack {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	return response, nil
+}
+
+// processConcurrently applies the valid operations using the worker pool
+func (p *bulkPresenter) processConcurrently(ctx context.Context, ops []models.BulkOperation, results []models.BulkResult) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < p.workers; w++ {
+		wg.Add(1)
+		p.spawn(ctx, "worker", func(ctx context.Context) {
+			defer wg.Done()
+			// Each index is owned by exactly one worker, so results can be
+			// written without locking
+			for i := range jobs {
+				results[i] = applyBulkOperation(ctx, p.productRepo, i, ops[i])
+			}
+		})
+	}
+
+	defer wg.Wait()
+	defer close(jobs)
+
+	for i := range ops {
+		if results[i].Status == models.BulkStatusFailed {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processAtomic applies all operations in a single transaction, rolling
+// every one of them back if any fails
+func (p *bulkPresenter) processAtomic(ctx context.Context, ops []models.BulkOperation, results []models.BulkResult) {
+	failed := -1
+	err := p.productRepo.Transaction(ctx, func(repo repositories.ProductRepository) error {
+		for i, op := range ops {
+			results[i] = applyBulkOperation(ctx, repo, i, op)
+			if results[i].Status == models.BulkStatusFailed {
+				failed = i
+				return errors.New(results[i].Error)
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		return
+	}
+
+	// The commit itself may fail after every operation succeeded
+	if failed < 0 {
+		for i := range results {
+			results[i] = models.BulkResult{Index: i, Status: models.BulkStatusFailed, Error: publicError(err)}
+		}
+		return
+	}
+	rollBack(results)
+}
+
+// rollBack marks every operation that did not fail itself as rolled back
+func rollBack(results []models.BulkResult) {
+	for i := range results {
+		if results[i].Status != models.BulkStatusFailed {
+			results[i] = models.BulkResult{Index: i, Status: models.BulkStatusRolledBack, Error: errRolledBack.Error()}
+		}
+	}
+}
+
+// applyBulkOperation applies a single validated operation through repo
+func applyBulkOperation(ctx context.Context, repo repositories.ProductRepository, index int, op models.BulkOperation) models.BulkResult {
+	result := models.BulkResult{Index: index, ID: op.ID}
+
+	var err error
+	switch op.Op {
+	case models.BulkCreate:
+		product := &models.Product{
+			Name:        op.Product.Name,
+			Description: op.Product.Description,
+			Price:       op.Product.Price,
+			Stock:       op.Product.Stock,
+			Version:     1,
+		}
+		if err = repo.Create(ctx, product); err == nil {
+			result.ID = product.ID
+			result.Status = models.BulkStatusCreated
+		}
+	case models.BulkUpdate:
+		var product *models.Product
+		if product, err = repo.GetByID(ctx, op.ID); err == nil {
+			if op.Version != 0 {
+				product.Version = op.Version
+			}
+			product.Name = op.Product.Name
+			product.Description = op.Product.Description
+			product.Price = op.Product.Price
+			product.Stock = op.Product.Stock
+			if err = repo.Update(ctx, product); err == nil {
+				result.Status = models.BulkStatusUpdated
+			}
+		}
+	case models.BulkDelete:
+		if err = repo.Delete(ctx, op.ID, op.Version); err == nil {
+			result.Status = models.BulkStatusDeleted
+		}
+	}
+
+	if err != nil {
+		result.Status = models.BulkStatusFailed
+		result.Error = publicError(err)
+	}
+	return result
+}
+
+// publicError returns the message a client is shown for an operation's
+// error. Domain errors keep their message. Anything else may carry SQL or
+// driver details, so it is logged and reported as an internal error, as the
+// HTTP error handler does.
+func publicError(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrConflict), errors.Is(err, ErrValidation), errors.Is(err, ErrTimeout):
+		return err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Error()
+	case errors.Is(err, context.Canceled):
+		return err.Error()
+	default:
+		log.Println("Bulk operation failed:", err)
+		return "internal error"
+	}
+}
@@ -0,0 +1,110 @@
+package presenters
+
+import (
+	"context"
+	"errors"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"simple-goroutine-product/internal/validators"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBulkPresenter_ProcessBulkBestEffort(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewBulkPresenter(mockRepo, 4, metrics.Nop{})
+
+	var nextID uint32
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Product).ID = uint(atomic.AddUint32(&nextID, 1))
+	})
+	mockRepo.On("Delete", mock.Anything, uint(9), uint(0)).Return(errors.New(`pq: update or delete on table "products" violates foreign key constraint`))
+	mockRepo.On("Delete", mock.Anything, uint(10), uint(0)).Return(repositories.ErrProductNotFound)
+
+	ops := make([]models.BulkOperation, 0, 102)
+	for i := 0; i < 100; i++ {
+		ops = append(ops, models.BulkOperation{
+			Op:      models.BulkCreate,
+			Product: &models.ProductRequest{Name: "Product", Price: 10},
+		})
+	}
+	ops = append(ops, models.BulkOperation{Op: models.BulkDelete, ID: 9}, models.BulkOperation{Op: models.BulkDelete, ID: 10})
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ProcessBulk(ctx, models.BulkRequest{Operations: ops}, validate)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Results, 102)
+	assert.Equal(t, 100, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+
+	ids := make(map[uint]bool)
+	for i, r := range result.Results[:100] {
+		assert.Equal(t, i, r.Index)
+		assert.Equal(t, models.BulkStatusCreated, r.Status)
+		ids[r.ID] = true
+	}
+	assert.Len(t, ids, 100)
+	assert.Equal(t, models.BulkStatusFailed, result.Results[100].Status)
+	// Driver errors are hidden, domain errors keep their message
+	assert.Equal(t, "internal error", result.Results[100].Error)
+	assert.Equal(t, "product not found", result.Results[101].Error)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBulkPresenter_ProcessBulkInvalidOperation(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewBulkPresenter(mockRepo, 2, metrics.Nop{})
+
+	ops := []models.BulkOperation{
+		{Op: models.BulkUpdate, Product: &models.ProductRequest{Name: "Missing ID", Price: 10}},
+		{Op: models.BulkCreate},
+		{Op: "upsert", ID: 1},
+	}
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ProcessBulk(ctx, models.BulkRequest{Operations: ops}, validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Failed)
+	for _, r := range result.Results {
+		assert.Equal(t, models.BulkStatusFailed, r.Status)
+		assert.NotEmpty(t, r.Error)
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestBulkPresenter_ProcessBulkAtomic(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewBulkPresenter(repo, 4, metrics.Nop{})
+
+	existing := &models.Product{Name: "Existing", Price: 10, Version: 1}
+	repo.Create(context.Background(), existing)
+
+	ops := []models.BulkOperation{
+		{Op: models.BulkCreate, Product: &models.ProductRequest{Name: "New", Price: 20}},
+		{Op: models.BulkUpdate, ID: existing.ID, Version: 1, Product: &models.ProductRequest{Name: "Renamed", Price: 15}},
+		{Op: models.BulkUpdate, ID: existing.ID, Version: 1, Product: &models.ProductRequest{Name: "Stale", Price: 15}},
+	}
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ProcessBulk(ctx, models.BulkRequest{Atomic: true, Operations: ops}, validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Succeeded)
+	assert.Equal(t, models.BulkStatusRolledBack, result.Results[0].Status)
+	assert.Equal(t, models.BulkStatusRolledBack, result.Results[1].Status)
+	assert.Equal(t, models.BulkStatusFailed, result.Results[2].Status)
+
+	// Nothing from the batch may remain
+	products := listProducts(t, repo)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Existing", products[0].Name)
+}
@@ -0,0 +1,216 @@
+package presenters
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportFormat identifies the format of a product export
+type ExportFormat int
+
+const (
+	// ExportCSV is a comma separated file with a header row
+	ExportCSV ExportFormat = iota
+	// ExportNDJSON is newline delimited JSON with one product per line
+	ExportNDJSON
+	// ExportXLSX is an Excel workbook with a single sheet
+	ExportXLSX
+)
+
+// ExportPresenter interface for streaming product exports
+type ExportPresenter interface {
+	ExportProducts(ctx context.Context, format ExportFormat, query models.ProductQuery, w io.Writer) error
+}
+
+// exportFlushInterval is the number of rows written between flushes
+const exportFlushInterval = 500
+
+// exportColumns are the columns written by every export format
+var exportColumns = []string{"id", "name", "description", "price", "stock", "version", "created_at", "updated_at"}
+
+// flusher is implemented by writers that can push buffered data to the client
+type flusher interface {
+	Flush()
+}
+
+// exportPresenter implements ExportPresenter
+type exportPresenter struct {
+	background
+	productRepo repositories.ProductRepository
+}
+
+// NewExportPresenter creates a new export presenter whose exports are
+// recorded with recorder
+func NewExportPresenter(productRepo repositories.ProductRepository, recorder metrics.Recorder) ExportPresenter {
+	return &exportPresenter{
+		background:  background{name: "export", metrics: recorder},
+		productRepo: productRepo,
+	}
+}
+
+// ExportProducts writes every product matching the query's filters to w.
+// Rows are streamed from the repository so memory use does not grow with
+// the size of the catalog.
+func (p *exportPresenter) ExportProducts(ctx context.Context, format ExportFormat, query models.ProductQuery, w io.Writer) (err error) {
+	ctx, end := p.start(ctx, "products")
+	defer end(&err)
+
+	switch format {
+	case ExportCSV:
+		return p.exportCSV(ctx, query, w)
+	case ExportNDJSON:
+		return p.exportNDJSON(ctx, query, w)
+	case ExportXLSX:
+		return p.exportXLSX(ctx, query, w)
+	default:
+		return fmt.Errorf("unsupported export format %d", format)
+	}
+}
+
+// exportCSV writes products as CSV with a header row
+func (p *exportPresenter) exportCSV(ctx context.Context, query models.ProductQuery, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportColumns); err != nil {
+		return err
+	}
+
+	err := p.stream(ctx, query, w, func(product *models.Product) error {
+		return writer.Write(exportRecord(product))
+	}, writer.Flush)
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportNDJSON writes products as one JSON object per line
+func (p *exportPresenter) exportNDJSON(ctx context.Context, query models.ProductQuery, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return p.stream(ctx, query, w, func(product *models.Product) error {
+		return encoder.Encode(exportObject{
+			ID:          product.ID,
+			Name:        product.Name,
+			Description: product.Description,
+			Price:       product.Price,
+			Stock:       product.Stock,
+			Version:     product.Version,
+			CreatedAt:   product.CreatedAt,
+			UpdatedAt:   product.UpdatedAt,
+		})
+	}, nil)
+}
+
+// exportXLSX writes products to a workbook. A workbook is a zip archive that
+// can only be written once every row is known, so nothing is sent to w until
+// the last row has been read. The stream writer keeps rows in memory until
+// they pass its chunk size and spills them to a temporary file after that.
+func (p *exportPresenter) exportXLSX(ctx context.Context, query models.ProductQuery, w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheet, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = column
+	}
+	if err := sheet.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	row := 1
+	err = p.stream(ctx, query, nil, func(product *models.Product) error {
+		row++
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return sheet.SetRow(cell, []interface{}{
+			product.ID,
+			product.Name,
+			product.Description,
+			product.Price,
+			product.Stock,
+			product.Version,
+			product.CreatedAt.UTC().Format(time.RFC3339),
+			product.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// stream calls write for every matching product, stopping when ctx is
+// cancelled. Every exportFlushInterval rows flush is called and w is flushed
+// if it supports it.
+func (p *exportPresenter) stream(ctx context.Context, query models.ProductQuery, w io.Writer, write func(product *models.Product) error, flush func()) error {
+	rows := 0
+	return p.productRepo.Stream(ctx, query, func(product *models.Product) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := write(product); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushInterval == 0 {
+			if flush != nil {
+				flush()
+			}
+			if f, ok := w.(flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	})
+}
+
+// exportRecord converts a product to a CSV record in exportColumns order
+func exportRecord(product *models.Product) []string {
+	return []string{
+		strconv.FormatUint(uint64(product.ID), 10),
+		product.Name,
+		product.Description,
+		strconv.FormatFloat(product.Price, 'f', -1, 64),
+		strconv.Itoa(product.Stock),
+		strconv.FormatUint(uint64(product.Version), 10),
+		product.CreatedAt.UTC().Format(time.RFC3339),
+		product.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// exportObject is the NDJSON representation of a product
+type exportObject struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+	Stock       int       `json:"stock"`
+	Version     uint      `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,87 @@
+package presenters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func newExportRepository() repositories.ProductRepository {
+	repo := newMemoryProductRepository()
+	repo.Create(context.Background(), &models.Product{Name: "Keyboard", Description: "Mechanical, US layout", Price: 49.9, Stock: 10, Version: 1})
+	repo.Create(context.Background(), &models.Product{Name: "Mouse", Price: 19.5, Stock: 0, Version: 3})
+	return repo
+}
+
+func TestExportPresenter_ExportCSV(t *testing.T) {
+	presenter := NewExportPresenter(newExportRepository(), metrics.Nop{})
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	err := presenter.ExportProducts(ctx, ExportCSV, models.ProductQuery{}, &buf)
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "id,name,description,price,stock,version,created_at,updated_at", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], `1,Keyboard,"Mechanical, US layout",49.9,10,1,`))
+	assert.True(t, strings.HasPrefix(lines[2], "2,Mouse,,19.5,0,3,"))
+}
+
+func TestExportPresenter_ExportNDJSON(t *testing.T) {
+	presenter := NewExportPresenter(newExportRepository(), metrics.Nop{})
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	err := presenter.ExportProducts(ctx, ExportNDJSON, models.ProductQuery{}, &buf)
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var product map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &product))
+	assert.Equal(t, "Mouse", product["name"])
+	assert.Equal(t, 19.5, product["price"])
+}
+
+func TestExportPresenter_ExportXLSX(t *testing.T) {
+	presenter := NewExportPresenter(newExportRepository(), metrics.Nop{})
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+	err := presenter.ExportProducts(ctx, ExportXLSX, models.ProductQuery{}, &buf)
+	assert.NoError(t, err)
+
+	file, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	rows, err := file.GetRows("Sheet1")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "name", rows[0][1])
+	assert.Equal(t, "Keyboard", rows[1][1])
+	assert.Equal(t, "Mouse", rows[2][1])
+}
+
+func TestExportPresenter_ExportCancelled(t *testing.T) {
+	presenter := NewExportPresenter(newExportRepository(), metrics.Nop{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	err := presenter.ExportProducts(ctx, ExportNDJSON, models.ProductQuery{}, &buf)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, buf.String())
+}
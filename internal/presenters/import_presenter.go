@@ -0,0 +1,343 @@
+package presenters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"simple-goroutine-product/internal/apperrors"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"strconv"
+	"strings"
+)
+
+// ImportFormat identifies the format of a product import stream
+type ImportFormat int
+
+const (
+	// ImportCSV is a comma separated file with a header row
+	ImportCSV ImportFormat = iota
+	// ImportNDJSON is newline delimited JSON with one product per line
+	ImportNDJSON
+)
+
+// ImportPresenter interface for streaming product imports
+type ImportPresenter interface {
+	ImportProducts(ctx context.Context, format ImportFormat, r io.Reader, dryRun bool, validate func(interface{}) error) (*models.ImportResult, error)
+}
+
+// ErrInvalidImport is returned when an import stream cannot be read at all,
+// for example because the CSV header is missing or names unknown columns
+var ErrInvalidImport = apperrors.New(ErrValidation, "invalid import")
+
+// importBatchSize is the number of rows written per repository call
+const importBatchSize = 500
+
+// maxImportLineSize is the longest NDJSON line accepted
+const maxImportLineSize = 1 << 20
+
+// importPresenter implements ImportPresenter
+type importPresenter struct {
+	background
+	productRepo repositories.ProductRepository
+}
+
+// NewImportPresenter creates a new import presenter whose imports are
+// recorded with recorder
+func NewImportPresenter(productRepo repositories.ProductRepository, recorder metrics.Recorder) ImportPresenter {
+	return &importPresenter{
+		background:  background{name: "import", metrics: recorder},
+		productRepo: productRepo,
+	}
+}
+
+// importLine is a parsed row together with the line it came from
+type importLine struct {
+	line int
+	row  models.ProductImportRow
+}
+
+// importer accumulates rows into batches and tracks the import result
+type importer struct {
+	ctx      context.Context
+	repo     repositories.ProductRepository
+	validate func(interface{}) error
+	result   *models.ImportResult
+	batch    []importLine
+	// committed is set once a batch has been written
+	committed bool
+}
+
+// errDryRun rolls back the transaction a dry run writes its batches in
+var errDryRun = errors.New("dry run")
+
+// ImportProducts streams rows from r, validates each one and upserts them in
+// batches. Only one batch is held in memory at a time. With dryRun every
+// batch is written in a transaction that is rolled back, so the result
+// predicts the real import without changing anything. When the import stops
+// part way after batches were written, those rows stay written and the
+// partial result is returned together with the error.
+func (p *importPresenter) ImportProducts(ctx context.Context, format ImportFormat, r io.Reader, dryRun bool, validate func(interface{}) error) (_ *models.ImportResult, err error) {
+	ctx, end := p.start(ctx, "products")
+	defer end(&err)
+
+	imp := &importer{
+		ctx:      ctx,
+		repo:     p.productRepo,
+		validate: validate,
+		result:   &models.ImportResult{DryRun: dryRun, Errors: []models.ImportError{}},
+	}
+
+	switch format {
+	case ImportCSV:
+		err = readCSV(r, imp.add)
+	case ImportNDJSON:
+		err = readNDJSON(r, imp.add)
+	default:
+		err = fmt.Errorf("%w: unsupported format %d", ErrInvalidImport, format)
+	}
+	if err == nil {
+		err = imp.flush()
+	}
+	if err != nil {
+		if imp.committed {
+			return imp.result, err
+		}
+		return nil, err
+	}
+
+	return imp.result, nil
+}
+
+// add validates a parsed row and queues it for writing. A non-nil rowErr
+// records a row that failed to parse.
+func (imp *importer) add(line int, row models.ProductImportRow, rowErr error) error {
+	imp.result.Processed++
+
+	if rowErr == nil {
+		rowErr = imp.validate(row.ProductRequest)
+	}
+	if rowErr != nil {
+		imp.fail(line, rowErr.Error())
+		return nil
+	}
+
+	imp.batch = append(imp.batch, importLine{line: line, row: row})
+	if len(imp.batch) >= importBatchSize {
+		return imp.flush()
+	}
+	return nil
+}
+
+// flush writes the queued batch
+func (imp *importer) flush() error {
+	if len(imp.batch) == 0 {
+		return nil
+	}
+	if err := imp.ctx.Err(); err != nil {
+		return err
+	}
+
+	products := make([]models.Product, len(imp.batch))
+	for i, item := range imp.batch {
+		products[i] = models.Product{
+			ID:          item.row.ID,
+			Name:        item.row.Name,
+			Description: item.row.Description,
+			Price:       item.row.Price,
+			Stock:       item.row.Stock,
+		}
+	}
+
+	rejected, err := imp.upsert(products)
+	if err != nil {
+		return err
+	}
+	imp.committed = !imp.result.DryRun
+
+	for i, item := range imp.batch {
+		if err, ok := rejected[i]; ok {
+			if errors.Is(err, repositories.ErrProductNotFound) {
+				imp.fail(item.line, fmt.Sprintf("product %d not found", item.row.ID))
+			} else {
+				imp.fail(item.line, fmt.Sprintf("product %d: %v", item.row.ID, err))
+			}
+			continue
+		}
+		imp.count(item.row.ID)
+	}
+
+	imp.batch = imp.batch[:0]
+	return nil
+}
+
+// upsert writes a batch. A dry run writes it in a transaction that is rolled
+// back, so missing products and stock below active holds are reported
+// exactly as a real import would report them.
+func (imp *importer) upsert(products []models.Product) (map[int]error, error) {
+	if !imp.result.DryRun {
+		return imp.repo.UpsertBatch(imp.ctx, products)
+	}
+
+	var rejected map[int]error
+	err := imp.repo.Transaction(imp.ctx, func(repo repositories.ProductRepository) error {
+		var err error
+		if rejected, err = repo.UpsertBatch(imp.ctx, products); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return rejected, err
+}
+
+// count records a successfully imported row
+func (imp *importer) count(id uint) {
+	if id == 0 {
+		imp.result.Created++
+	} else {
+		imp.result.Updated++
+	}
+}
+
+// fail records a row that could not be imported
+func (imp *importer) fail(line int, message string) {
+	imp.result.Failed++
+	if len(imp.result.Errors) < models.MaxImportErrors {
+		imp.result.Errors = append(imp.result.Errors, models.ImportError{Line: line, Error: message})
+	}
+}
+
+// readCSV parses a CSV stream with a header row and calls add for each record
+func readCSV(r io.Reader, add func(line int, row models.ProductImportRow, rowErr error) error) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "id", "name", "description", "price", "stock":
+		default:
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("%w: duplicate column %q", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return fmt.Errorf("%w: missing column \"name\"", ErrInvalidImport)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := add(parseErr.StartLine, models.ProductImportRow{}, parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, rowErr := parseCSVRecord(record, columns)
+		if err := add(line, row, rowErr); err != nil {
+			return err
+		}
+	}
+}
+
+// parseCSVRecord converts a CSV record into an import row
+func parseCSVRecord(record []string, columns map[string]int) (models.ProductImportRow, error) {
+	var row models.ProductImportRow
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row.Name = field("name")
+	row.Description = field("description")
+
+	if v := field("id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("invalid id %q", v)
+		}
+		row.ID = uint(id)
+	}
+
+	if v := field("price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return row, fmt.Errorf("invalid price %q", v)
+		}
+		row.Price = price
+	}
+
+	if v := field("stock"); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil {
+			return row, fmt.Errorf("invalid stock %q", v)
+		}
+		row.Stock = stock
+	}
+
+	return row, nil
+}
+
+// readNDJSON parses a newline delimited JSON stream and calls add for each
+// non-blank line
+func readNDJSON(r io.Reader, add func(line int, row models.ProductImportRow, rowErr error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var row models.ProductImportRow
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		rowErr := decoder.Decode(&row)
+
+		if err := add(line, row, rowErr); err != nil {
+			return err
+		}
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("%w: line %d exceeds %d bytes", ErrInvalidImport, line+1, maxImportLineSize)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,160 @@
+package presenters
+
+import (
+	"context"
+	"fmt"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"simple-goroutine-product/internal/validators"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImportPresenter_ImportCSV(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewImportPresenter(repo, metrics.Nop{})
+
+	existing := &models.Product{Name: "Old Name", Price: 1, Version: 1}
+	repo.Create(context.Background(), existing)
+
+	csv := strings.Join([]string{
+		"id,name,description,price,stock",
+		",Keyboard,Mechanical,49.90,10",
+		"1,New Name,,2.50,3",
+		",Mouse,,not-a-price,1",
+		",,Missing name,5,1",
+		"42,Ghost,,5,1",
+		",\"Monitor, 27\"\"\",IPS,199,4",
+	}, "\n")
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ImportProducts(ctx, ImportCSV, strings.NewReader(csv), false, validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, result.Processed)
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 3, result.Failed)
+
+	lines := make([]int, len(result.Errors))
+	for i, e := range result.Errors {
+		lines[i] = e.Line
+	}
+	assert.Equal(t, []int{4, 5, 6}, lines)
+
+	products := listProducts(t, repo)
+	assert.Len(t, products, 3)
+	assert.Equal(t, "New Name", products[0].Name)
+	assert.Equal(t, "Monitor, 27\"", products[2].Name)
+}
+
+func TestImportPresenter_ImportCSVUnknownColumn(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewImportPresenter(repo, metrics.Nop{})
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ImportProducts(ctx, ImportCSV, strings.NewReader("name,sku\nCable,C-1\n"), false, validate)
+
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	assert.Nil(t, result)
+}
+
+func TestImportPresenter_ImportNDJSONDryRun(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewImportPresenter(mockRepo, metrics.Nop{})
+
+	ndjson := strings.Join([]string{
+		`{"name": "Cable", "price": 4.5, "stock": 100}`,
+		``,
+		`{"name": "Charger", "price": -1}`,
+		`{"name": "Hub", "price": 30, "color": "black"}`,
+		`{"id": 3, "name": "Adapter", "price": 12}`,
+		`not json`,
+	}, "\n")
+
+	// The batch is written in a transaction that the dry run rolls back
+	mockRepo.On("UpsertBatch", mock.Anything, mock.AnythingOfType("[]models.Product")).Return(map[int]error{1: repositories.ErrProductNotFound}, nil)
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ImportProducts(ctx, ImportNDJSON, strings.NewReader(ndjson), true, validate)
+
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 5, result.Processed)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, 4, result.Failed)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Equal(t, 4, result.Errors[1].Line)
+	assert.Equal(t, 6, result.Errors[2].Line)
+	assert.Equal(t, models.ImportError{Line: 5, Error: "product 3 not found"}, result.Errors[3])
+}
+
+func TestImportPresenter_DryRunWritesNothing(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewImportPresenter(repo, metrics.Nop{})
+
+	existing := &models.Product{Name: "Cable", Price: 1, Version: 1}
+	repo.Create(context.Background(), existing)
+
+	csv := "id,name,price\n1,USB Cable,2\n,Charger,20\n42,Ghost,5\n"
+	result, err := presenter.ImportProducts(context.Background(), ImportCSV, strings.NewReader(csv), true, validators.NewValidator().Validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Failed)
+
+	products := listProducts(t, repo)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Cable", products[0].Name)
+}
+
+func TestImportPresenter_AbortedReturnsPartialResult(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewImportPresenter(repo, metrics.Nop{})
+
+	var ndjson strings.Builder
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&ndjson, "{\"name\": \"Product %d\", \"price\": %d}\n", i, i+1)
+	}
+	ndjson.WriteString(strings.Repeat("x", maxImportLineSize+1))
+
+	result, err := presenter.ImportProducts(context.Background(), ImportNDJSON, strings.NewReader(ndjson.String()), false, validators.NewValidator().Validate)
+
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, 500, result.Created)
+	}
+	assert.Len(t, listProducts(t, repo), 500)
+}
+
+func TestImportPresenter_ImportBatches(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewImportPresenter(mockRepo, metrics.Nop{})
+
+	var sizes []int
+	mockRepo.On("UpsertBatch", mock.Anything, mock.AnythingOfType("[]models.Product")).Return(nil, nil).Run(func(args mock.Arguments) {
+		sizes = append(sizes, len(args.Get(1).([]models.Product)))
+	})
+
+	var ndjson strings.Builder
+	for i := 0; i < 1234; i++ {
+		fmt.Fprintf(&ndjson, "{\"name\": \"Product %d\", \"price\": %d}\n", i, i+1)
+	}
+
+	ctx := context.Background()
+	validate := validators.NewValidator().Validate
+	result, err := presenter.ImportProducts(ctx, ImportNDJSON, strings.NewReader(ndjson.String()), false, validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, result.Created)
+	assert.Equal(t, []int{500, 500, 234}, sizes)
+}
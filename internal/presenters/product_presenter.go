@@ -1,36 +1,112 @@
 package presenters
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"simple-goroutine-product/internal/apperrors"
+	"simple-goroutine-product/internal/metrics"
 	"simple-goroutine-product/internal/models"
 	"simple-goroutine-product/internal/repositories"
 	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
 )
 
 // ProductPresenter interface for business logic
 type ProductPresenter interface {
 	CreateProduct(ctx context.Context, req models.ProductRequest) (*models.ProductResponse, error)
 	GetProduct(ctx context.Context, id uint) (*models.ProductResponse, error)
-	GetProducts(ctx context.Context, page, limit int) ([]models.ProductResponse, int64, error)
-	UpdateProduct(ctx context.Context, id uint, req models.ProductRequest) (*models.ProductResponse, error)
-	DeleteProduct(ctx context.Context, id uint) error
+	GetProducts(ctx context.Context, query models.ProductQuery) ([]models.ProductResponse, int64, error)
+	GetProductsByCursor(ctx context.Context, query models.ProductQuery) (*models.ProductCursorPage, error)
+	UpdateProduct(ctx context.Context, id uint, req models.ProductRequest, version uint) (*models.ProductResponse, error)
+	PatchProduct(ctx context.Context, id uint, format PatchFormat, patch []byte, version uint, validate func(interface{}) error) (*models.ProductResponse, error)
+	AdjustStock(ctx context.Context, id uint, req models.StockAdjustmentRequest) (*models.ProductResponse, error)
+	DeleteProduct(ctx context.Context, id uint, version uint) error
+	GetDeletedProducts(ctx context.Context, query models.ProductQuery) ([]models.ProductResponse, int64, error)
+	RestoreProduct(ctx context.Context, id uint) (*models.ProductResponse, error)
+	PurgeProduct(ctx context.Context, id uint, version uint) error
+	PurgeDeletedProducts(ctx context.Context, olderThan time.Duration) (int64, error)
+	GetProductHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditResponse, int64, error)
+	RunPurge(ctx context.Context, interval, olderThan time.Duration)
+	Wait(ctx context.Context) error
+	InFlight() int
 }
 
+// PatchFormat identifies the format of a partial update document
+type PatchFormat int
+
+const (
+	// MergePatch is an RFC 7396 JSON Merge Patch document
+	MergePatch PatchFormat = iota
+	// JSONPatch is an RFC 6902 JSON Patch document
+	JSONPatch
+)
+
+// Domain error kinds shared with the repositories and validators
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = apperrors.ErrNotFound
+	// ErrConflict is returned when a write conflicts with the current state
+	// of a record
+	ErrConflict = apperrors.ErrConflict
+	// ErrValidation is returned when input is rejected before it is written
+	ErrValidation = apperrors.ErrValidation
+	// ErrTimeout is returned when an operation does not finish in time
+	ErrTimeout = apperrors.ErrTimeout
+)
+
+// ErrVersionConflict is returned when a product was changed since the
+// version the caller expected
+var ErrVersionConflict = repositories.ErrVersionConflict
+
+// ErrProductNotDeleted is returned when restoring a product that is not in
+// the trash
+var ErrProductNotDeleted = repositories.ErrProductNotDeleted
+
+// ErrInsufficientStock is returned when a stock change would make the stock
+// negative or take it below the quantity held by active reservations
+var ErrInsufficientStock = repositories.ErrInsufficientStock
+
+// ErrInvalidPatch is returned when a patch document is malformed or cannot
+// be applied to the product
+var ErrInvalidPatch = apperrors.New(ErrValidation, "invalid patch")
+
+// ErrUnprocessablePatch is returned when a well-formed patch nulls or
+// removes a product field, or produces a product that fails validation.
+// Every field is required, so a removed member would otherwise silently
+// become its zero value.
+var ErrUnprocessablePatch = apperrors.New(ErrInvalidPatch, "patch cannot be applied to the product")
+
+// ErrPatchTestFailed is returned when a JSON Patch test operation does not hold
+var ErrPatchTestFailed = apperrors.New(ErrConflict, "patch test operation failed")
+
 // productPresenter implements ProductPresenter
 type productPresenter struct {
+	background
 	productRepo repositories.ProductRepository
+	timeout     time.Duration
 }
 
-// NewProductPresenter creates a new product presenter
-func NewProductPresenter(productRepo repositories.ProductRepository) ProductPresenter {
+// NewProductPresenter creates a new product presenter. Writes that do not
+// finish within timeout fail with ErrTimeout, and every write is recorded
+// with recorder.
+func NewProductPresenter(productRepo repositories.ProductRepository, timeout time.Duration, recorder metrics.Recorder) ProductPresenter {
 	return &productPresenter{
+		background:  background{name: "product", metrics: recorder},
 		productRepo: productRepo,
+		timeout:     timeout,
 	}
 }
 
 // CreateProduct creates a new product using goroutine
-func (p *productPresenter) CreateProduct(ctx context.Context, req models.ProductRequest) (*models.ProductResponse, error) {
+func (p *productPresenter) CreateProduct(ctx context.Context, req models.ProductRequest) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "create")
+	defer end(&err)
+
 	// Channel to receive result from goroutine
 	resultChan := make(chan struct {
 		product *models.Product
@@ -38,20 +114,21 @@ func (p *productPresenter) CreateProduct(ctx context.Context, req models.Product
 	}, 1)
 
 	// Execute create operation in goroutine
-	go func() {
+	p.spawn(ctx, "create", func(ctx context.Context) {
 		product := &models.Product{
 			Name:        req.Name,
 			Description: req.Description,
 			Price:       req.Price,
 			Stock:       req.Stock,
+			Version:     1,
 		}
 
-		err := p.productRepo.Create(product)
+		err := p.productRepo.Create(ctx, product)
 		resultChan <- struct {
 			product *models.Product
 			err     error
 		}{product: product, err: err}
-	}()
+	})
 
 	// Wait for result with timeout
 	select {
@@ -63,29 +140,31 @@ func (p *productPresenter) CreateProduct(ctx context.Context, req models.Product
 		return &response, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-time.After(30 * time.Second):
-		return nil, errors.New("operation timeout")
+	case <-time.After(p.timeout):
+		return nil, ErrTimeout
 	}
 }
 
 // GetProduct gets a product by ID
-func (p *productPresenter) GetProduct(ctx context.Context, id uint) (*models.ProductResponse, error) {
-	product, err := p.productRepo.GetByID(id)
+func (p *productPresenter) GetProduct(ctx context.Context, id uint) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "get")
+	defer end(&err)
+
+	product, err := p.productRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if product == nil {
-		return nil, nil
-	}
-
 	response := product.ToResponse()
 	return &response, nil
 }
 
 // GetProducts gets all products with pagination
-func (p *productPresenter) GetProducts(ctx context.Context, page, limit int) ([]models.ProductResponse, int64, error) {
-	products, total, err := p.productRepo.GetAll(page, limit)
+func (p *productPresenter) GetProducts(ctx context.Context, query models.ProductQuery) (_ []models.ProductResponse, _ int64, err error) {
+	ctx, end := p.start(ctx, "list")
+	defer end(&err)
+
+	products, total, err := p.productRepo.GetAll(ctx, query)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -98,8 +177,45 @@ func (p *productPresenter) GetProducts(ctx context.Context, page, limit int) ([]
 	return responses, total, nil
 }
 
-// UpdateProduct updates a product using goroutine
-func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req models.ProductRequest) (*models.ProductResponse, error) {
+// GetProductsByCursor gets a page of products using keyset pagination
+func (p *productPresenter) GetProductsByCursor(ctx context.Context, query models.ProductQuery) (_ *models.ProductCursorPage, err error) {
+	ctx, end := p.start(ctx, "list_cursor")
+	defer end(&err)
+
+	products, hasMore, err := p.productRepo.GetAllByCursor(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ProductCursorPage{Limit: query.Limit}
+	for _, product := range products {
+		page.Data = append(page.Data, product.ToResponse())
+	}
+
+	if len(products) == 0 {
+		return page, nil
+	}
+
+	// A backward page always has rows after it, and a forward page after a
+	// cursor always has rows before it
+	sort := query.KeysetSort()
+	backward := query.Cursor != nil && query.Cursor.Backward
+	if hasMore || backward {
+		page.NextCursor = models.NewCursor(sort, &products[len(products)-1], false).Encode()
+	}
+	if (hasMore && backward) || (!backward && query.Cursor != nil) {
+		page.PrevCursor = models.NewCursor(sort, &products[0], true).Encode()
+	}
+
+	return page, nil
+}
+
+// UpdateProduct updates a product using goroutine. A non-zero version is the
+// version the caller expects to overwrite; zero uses the version just read.
+func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req models.ProductRequest, version uint) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "update")
+	defer end(&err)
+
 	// Channel to receive result from goroutine
 	resultChan := make(chan struct {
 		product *models.Product
@@ -107,9 +223,9 @@ func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req model
 	}, 1)
 
 	// Execute update operation in goroutine
-	go func() {
+	p.spawn(ctx, "update", func(ctx context.Context) {
 		// First get the existing product
-		product, err := p.productRepo.GetByID(id)
+		product, err := p.productRepo.GetByID(ctx, id)
 		if err != nil {
 			resultChan <- struct {
 				product *models.Product
@@ -118,6 +234,10 @@ func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req model
 			return
 		}
 
+		if version != 0 {
+			product.Version = version
+		}
+
 		// Update fields
 		product.Name = req.Name
 		product.Description = req.Description
@@ -125,12 +245,195 @@ func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req model
 		product.Stock = req.Stock
 
 		// Save updated product
-		err = p.productRepo.Update(product)
+		err = p.productRepo.Update(ctx, product)
+		resultChan <- struct {
+			product *models.Product
+			err     error
+		}{product: product, err: err}
+	})
+
+	// Wait for result with timeout
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		response := result.product.ToResponse()
+		return &response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// PatchProduct partially updates a product using goroutine. The patch is
+// applied to the product's current request representation, the result is
+// checked with validate and only the fields that changed are written.
+func (p *productPresenter) PatchProduct(ctx context.Context, id uint, format PatchFormat, patch []byte, version uint, validate func(interface{}) error) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "patch")
+	defer end(&err)
+
+	// Channel to receive result from goroutine
+	resultChan := make(chan struct {
+		product *models.Product
+		err     error
+	}, 1)
+
+	// Execute patch operation in goroutine
+	p.spawn(ctx, "patch", func(ctx context.Context) {
+		product, err := p.patchProduct(ctx, id, format, patch, version, validate)
+		resultChan <- struct {
+			product *models.Product
+			err     error
+		}{product: product, err: err}
+	})
+
+	// Wait for result with timeout
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		response := result.product.ToResponse()
+		return &response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// patchProduct loads, patches, validates and saves a product
+func (p *productPresenter) patchProduct(ctx context.Context, id uint, format PatchFormat, patch []byte, version uint, validate func(interface{}) error) (*models.Product, error) {
+	product, err := p.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && product.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	current := models.ProductRequest{
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+	}
+
+	req, err := applyPatch(current, format, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	// The patch itself was fine, but the product it produces is not
+	if err := validate(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnprocessablePatch, err)
+	}
+
+	// Only write the columns the patch actually changed
+	var fields []string
+	if req.Name != current.Name {
+		product.Name = req.Name
+		fields = append(fields, "Name")
+	}
+	if req.Description != current.Description {
+		product.Description = req.Description
+		fields = append(fields, "Description")
+	}
+	if req.Price != current.Price {
+		product.Price = req.Price
+		fields = append(fields, "Price")
+	}
+	if req.Stock != current.Stock {
+		product.Stock = req.Stock
+		fields = append(fields, "Stock")
+	}
+
+	if len(fields) == 0 {
+		return product, nil
+	}
+
+	if err := p.productRepo.UpdateFields(ctx, product, fields...); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// requestFields are the JSON members of a product request
+var requestFields = []string{"name", "description", "price", "stock"}
+
+// applyPatch applies a patch document to a product request
+func applyPatch(current models.ProductRequest, format PatchFormat, patch []byte) (models.ProductRequest, error) {
+	var req models.ProductRequest
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return req, err
+	}
+
+	var patched []byte
+	switch format {
+	case MergePatch:
+		patched, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatch:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = ops.Apply(doc)
+		}
+	default:
+		err = fmt.Errorf("unsupported patch format %d", format)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return req, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+	}
+	if err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	// Reject members the patch nulled or removed, checked in the order the
+	// product marshals its fields
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &members); err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	for _, field := range requestFields {
+		if value, ok := members[field]; !ok || bytes.Equal(value, []byte("null")) {
+			return req, fmt.Errorf("%w: %s cannot be null or removed", ErrUnprocessablePatch, field)
+		}
+	}
+
+	// Reject fields that are not part of the product
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return req, nil
+}
+
+// AdjustStock atomically adds the requested delta to a product's stock using goroutine
+func (p *productPresenter) AdjustStock(ctx context.Context, id uint, req models.StockAdjustmentRequest) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "adjust_stock")
+	defer end(&err)
+
+	// Channel to receive result from goroutine
+	resultChan := make(chan struct {
+		product *models.Product
+		err     error
+	}, 1)
+
+	// Execute adjust operation in goroutine
+	p.spawn(ctx, "adjust_stock", func(ctx context.Context) {
+		product, err := p.productRepo.AdjustStock(ctx, id, req.Delta, req.Reason)
 		resultChan <- struct {
 			product *models.Product
 			err     error
 		}{product: product, err: err}
-	}()
+	})
 
 	// Wait for result with timeout
 	select {
@@ -142,12 +445,109 @@ func (p *productPresenter) UpdateProduct(ctx context.Context, id uint, req model
 		return &response, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-time.After(30 * time.Second):
-		return nil, errors.New("operation timeout")
+	case <-time.After(p.timeout):
+		return nil, ErrTimeout
 	}
 }
 
-// DeleteProduct deletes a product
-func (p *productPresenter) DeleteProduct(ctx context.Context, id uint) error {
-	return p.productRepo.Delete(id)
+// DeleteProduct deletes a product. A non-zero version makes the delete
+// conditional on the product not having changed.
+func (p *productPresenter) DeleteProduct(ctx context.Context, id uint, version uint) (err error) {
+	ctx, end := p.start(ctx, "delete")
+	defer end(&err)
+
+	return p.productRepo.Delete(ctx, id, version)
+}
+
+// GetDeletedProducts gets the products in the trash with pagination
+func (p *productPresenter) GetDeletedProducts(ctx context.Context, query models.ProductQuery) (_ []models.ProductResponse, _ int64, err error) {
+	ctx, end := p.start(ctx, "list_deleted")
+	defer end(&err)
+
+	products, total, err := p.productRepo.GetDeleted(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var responses []models.ProductResponse
+	for _, product := range products {
+		responses = append(responses, product.ToResponse())
+	}
+
+	return responses, total, nil
+}
+
+// RestoreProduct takes a deleted product out of the trash
+func (p *productPresenter) RestoreProduct(ctx context.Context, id uint) (_ *models.ProductResponse, err error) {
+	ctx, end := p.start(ctx, "restore")
+	defer end(&err)
+
+	product, err := p.productRepo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := product.ToResponse()
+	return &response, nil
+}
+
+// PurgeProduct permanently deletes a product, whether or not it is in the
+// trash. A non-zero version makes the delete conditional on the product not
+// having changed.
+func (p *productPresenter) PurgeProduct(ctx context.Context, id uint, version uint) (err error) {
+	ctx, end := p.start(ctx, "purge")
+	defer end(&err)
+
+	return p.productRepo.HardDelete(ctx, id, version)
+}
+
+// PurgeDeletedProducts permanently deletes the products that have been in
+// the trash for longer than olderThan
+func (p *productPresenter) PurgeDeletedProducts(ctx context.Context, olderThan time.Duration) (_ int64, err error) {
+	ctx, end := p.start(ctx, "purge_deleted")
+	defer end(&err)
+
+	return p.productRepo.PurgeDeleted(ctx, time.Now().Add(-olderThan))
+}
+
+// GetProductHistory gets the audit trail of a product with pagination, most
+// recent change first. Purged products keep their history.
+func (p *productPresenter) GetProductHistory(ctx context.Context, id uint, page, limit int) (_ []models.ProductAuditResponse, _ int64, err error) {
+	ctx, end := p.start(ctx, "history")
+	defer end(&err)
+
+	entries, total, err := p.productRepo.GetHistory(ctx, id, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := []models.ProductAuditResponse{}
+	for _, entry := range entries {
+		responses = append(responses, entry.ToResponse())
+	}
+
+	return responses, total, nil
+}
+
+// RunPurge empties the trash of products deleted more than olderThan ago
+// every interval until ctx is cancelled
+func (p *productPresenter) RunPurge(ctx context.Context, interval, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := p.PurgeDeletedProducts(ctx, olderThan)
+			if err != nil {
+				log.Println("Failed to purge deleted products:", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted products", purged)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
 }
@@ -2,7 +2,11 @@ package presenters
 
 import (
 	"context"
+	"simple-goroutine-product/internal/metrics"
 	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"simple-goroutine-product/internal/validators"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,37 +19,100 @@ type MockProductRepository struct {
 	mock.Mock
 }
 
-func (m *MockProductRepository) Create(product *models.Product) error {
-	args := m.Called(product)
+func (m *MockProductRepository) Create(ctx context.Context, product *models.Product) error {
+	args := m.Called(ctx, product)
 	return args.Error(0)
 }
 
-func (m *MockProductRepository) GetByID(id uint) (*models.Product, error) {
-	args := m.Called(id)
-	return args.Get(0).(*models.Product), args.Error(1)
+func (m *MockProductRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
+	args := m.Called(ctx, id)
+	product, _ := args.Get(0).(*models.Product)
+	return product, args.Error(1)
 }
 
-func (m *MockProductRepository) GetAll(page, limit int) ([]models.Product, int64, error) {
-	args := m.Called(page, limit)
+func (m *MockProductRepository) GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
+	args := m.Called(ctx, query)
 	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockProductRepository) Update(product *models.Product) error {
-	args := m.Called(product)
+func (m *MockProductRepository) GetAllByCursor(ctx context.Context, query models.ProductQuery) ([]models.Product, bool, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Product), args.Bool(1), args.Error(2)
+}
+
+func (m *MockProductRepository) Stream(ctx context.Context, query models.ProductQuery, fn func(product *models.Product) error) error {
+	args := m.Called(ctx, query, fn)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) Update(ctx context.Context, product *models.Product) error {
+	args := m.Called(ctx, product)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) UpdateFields(ctx context.Context, product *models.Product, fields ...string) error {
+	args := m.Called(ctx, product, fields)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) AdjustStock(ctx context.Context, id uint, delta int, reason string) (*models.Product, error) {
+	args := m.Called(ctx, id, delta, reason)
+	product, _ := args.Get(0).(*models.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductRepository) UpsertBatch(ctx context.Context, products []models.Product) (map[int]error, error) {
+	args := m.Called(ctx, products)
+	rejected, _ := args.Get(0).(map[int]error)
+	return rejected, args.Error(1)
+}
+
+func (m *MockProductRepository) Delete(ctx context.Context, id uint, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]models.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) Restore(ctx context.Context, id uint) (*models.Product, error) {
+	args := m.Called(ctx, id)
+	product, _ := args.Get(0).(*models.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductRepository) HardDelete(ctx context.Context, id uint, version uint) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
-func (m *MockProductRepository) Delete(id uint) error {
-	args := m.Called(id)
+func (m *MockProductRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductRepository) GetHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditEntry, int64, error) {
+	args := m.Called(ctx, id, page, limit)
+	return args.Get(0).([]models.ProductAuditEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockProductRepository) Truncate(ctx context.Context) error {
+	args := m.Called(ctx)
 	return args.Error(0)
 }
 
+func (m *MockProductRepository) Transaction(ctx context.Context, fn func(repo repositories.ProductRepository) error) error {
+	return fn(m)
+}
+
 func TestProductPresenter_CreateProduct(t *testing.T) {
 	mockRepo := new(MockProductRepository)
-	presenter := NewProductPresenter(mockRepo)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
 
-	mockRepo.On("Create", mock.AnythingOfType("*models.Product")).Return(nil).Run(func(args mock.Arguments) {
-		arg := args.Get(0).(*models.Product)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.Product)
 		arg.ID = 1
 		arg.CreatedAt = time.Now()
 		arg.UpdatedAt = time.Now()
@@ -70,7 +137,7 @@ func TestProductPresenter_CreateProduct(t *testing.T) {
 
 func TestProductPresenter_GetProduct(t *testing.T) {
 	mockRepo := new(MockProductRepository)
-	presenter := NewProductPresenter(mockRepo)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
 
 	product := &models.Product{
 		ID:          1,
@@ -82,7 +149,7 @@ func TestProductPresenter_GetProduct(t *testing.T) {
 		UpdatedAt:   time.Now(),
 	}
 
-	mockRepo.On("GetByID", uint(1)).Return(product, nil)
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(product, nil)
 
 	ctx := context.Background()
 	result, err := presenter.GetProduct(ctx, 1)
@@ -94,9 +161,39 @@ func TestProductPresenter_GetProduct(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestProductPresenter_GetProductNotFound(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	mockRepo.On("GetByID", mock.Anything, uint(42)).Return((*models.Product)(nil), repositories.ErrProductNotFound)
+
+	ctx := context.Background()
+	result, err := presenter.GetProduct(ctx, 42)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_UpdateProductNotFound(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	mockRepo.On("GetByID", mock.Anything, uint(42)).Return((*models.Product)(nil), repositories.ErrProductNotFound)
+
+	req := models.ProductRequest{Name: "Ghost", Price: 1}
+
+	ctx := context.Background()
+	result, err := presenter.UpdateProduct(ctx, 42, req, 0)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
 func TestProductPresenter_UpdateProduct(t *testing.T) {
 	mockRepo := new(MockProductRepository)
-	presenter := NewProductPresenter(mockRepo)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
 
 	existingProduct := &models.Product{
 		ID:          1,
@@ -104,12 +201,15 @@ func TestProductPresenter_UpdateProduct(t *testing.T) {
 		Description: "Old Description",
 		Price:       50.00,
 		Stock:       5,
+		Version:     1,
 		CreatedAt:   time.Now().Add(-time.Hour),
 		UpdatedAt:   time.Now().Add(-time.Hour),
 	}
 
-	mockRepo.On("GetByID", uint(1)).Return(existingProduct, nil)
-	mockRepo.On("Update", mock.AnythingOfType("*models.Product")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(existingProduct, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Product).Version++
+	})
 
 	req := models.ProductRequest{
 		Name:        "Updated Product",
@@ -119,23 +219,53 @@ func TestProductPresenter_UpdateProduct(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	result, err := presenter.UpdateProduct(ctx, 1, req)
+	result, err := presenter.UpdateProduct(ctx, 1, req, 0)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, req.Name, result.Name)
 	assert.Equal(t, req.Price, result.Price)
+	assert.Equal(t, uint(2), result.Version)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_UpdateProductVersionConflict(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	existingProduct := &models.Product{
+		ID:      1,
+		Name:    "Old Product",
+		Price:   50.00,
+		Version: 3,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(existingProduct, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+		return p.Version == 2
+	})).Return(ErrVersionConflict)
+
+	req := models.ProductRequest{
+		Name:  "Updated Product",
+		Price: 99.99,
+	}
+
+	ctx := context.Background()
+	result, err := presenter.UpdateProduct(ctx, 1, req, 2)
+
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Nil(t, result)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestProductPresenter_DeleteProduct(t *testing.T) {
 	mockRepo := new(MockProductRepository)
-	presenter := NewProductPresenter(mockRepo)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
 
-	mockRepo.On("Delete", uint(1)).Return(nil)
+	mockRepo.On("Delete", mock.Anything, uint(1), uint(0)).Return(nil)
 
 	ctx := context.Background()
-	err := presenter.DeleteProduct(ctx, 1)
+	err := presenter.DeleteProduct(ctx, 1, 0)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -143,7 +273,7 @@ func TestProductPresenter_DeleteProduct(t *testing.T) {
 
 func TestProductPresenter_GetProducts(t *testing.T) {
 	mockRepo := new(MockProductRepository)
-	presenter := NewProductPresenter(mockRepo)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
 
 	products := []models.Product{
 		{
@@ -162,10 +292,11 @@ func TestProductPresenter_GetProducts(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("GetAll", 1, 10).Return(products, int64(2), nil)
+	query := models.ProductQuery{Page: 1, Limit: 10}
+	mockRepo.On("GetAll", mock.Anything, query).Return(products, int64(2), nil)
 
 	ctx := context.Background()
-	result, total, err := presenter.GetProducts(ctx, 1, 10)
+	result, total, err := presenter.GetProducts(ctx, query)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -173,3 +304,303 @@ func TestProductPresenter_GetProducts(t *testing.T) {
 	assert.Len(t, result, 2)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestProductPresenter_GetProductsByCursor(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	products := []models.Product{
+		{ID: 3, Name: "Product 3", Price: 10},
+		{ID: 7, Name: "Product 7", Price: 20},
+	}
+
+	sort := models.SortField{Field: "price"}
+	cursor := models.NewCursor(sort, &models.Product{ID: 1, Price: 5}, false)
+	query := models.ProductQuery{Limit: 2, Cursor: &cursor}
+	mockRepo.On("GetAllByCursor", mock.Anything, query).Return(products, true, nil)
+
+	ctx := context.Background()
+	result, err := presenter.GetProductsByCursor(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Data, 2)
+	assert.Equal(t, 2, result.Limit)
+
+	next, err := models.DecodeCursor(result.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, sort, next.Sort)
+	assert.Equal(t, uint(7), next.ID)
+	assert.Equal(t, float64(20), next.Value)
+	assert.False(t, next.Backward)
+
+	prev, err := models.DecodeCursor(result.PrevCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), prev.ID)
+	assert.True(t, prev.Backward)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_GetProductsByCursorFirstPage(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	products := []models.Product{{ID: 1, Name: "Product 1"}}
+	query := models.ProductQuery{Limit: 10}
+	mockRepo.On("GetAllByCursor", mock.Anything, query).Return(products, false, nil)
+
+	ctx := context.Background()
+	result, err := presenter.GetProductsByCursor(ctx, query)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Data, 1)
+	assert.Empty(t, result.NextCursor)
+	assert.Empty(t, result.PrevCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_PatchProduct(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	existingProduct := &models.Product{
+		ID:          1,
+		Name:        "Product",
+		Description: "Description",
+		Price:       50.00,
+		Stock:       5,
+		Version:     1,
+	}
+
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(existingProduct, nil)
+	mockRepo.On("UpdateFields", mock.Anything, mock.AnythingOfType("*models.Product"), []string{"Stock"}).Return(nil)
+
+	ctx := context.Background()
+	validate := func(interface{}) error { return nil }
+	result, err := presenter.PatchProduct(ctx, 1, MergePatch, []byte(`{"stock": 12}`), 0, validate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, result.Stock)
+	assert.Equal(t, "Product", result.Name)
+	assert.Equal(t, 50.00, result.Price)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_PatchProductInvalid(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	existingProduct := &models.Product{ID: 1, Name: "Product", Price: 50.00, Stock: 5, Version: 1}
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(existingProduct, nil)
+
+	ctx := context.Background()
+	validate := func(interface{}) error { return nil }
+
+	tests := []struct {
+		name     string
+		format   PatchFormat
+		patch    string
+		expected error
+	}{
+		{"malformed merge patch", MergePatch, `{"stock":`, ErrInvalidPatch},
+		{"unknown field", MergePatch, `{"sku": "A-1"}`, ErrInvalidPatch},
+		{"wrong type", MergePatch, `{"price": "free"}`, ErrInvalidPatch},
+		{"malformed json patch", JSONPatch, `{"op": "replace"}`, ErrInvalidPatch},
+		{"failed test", JSONPatch, `[{"op": "test", "path": "/stock", "value": 4}, {"op": "replace", "path": "/stock", "value": 3}]`, ErrPatchTestFailed},
+		{"null stock", MergePatch, `{"stock": null}`, ErrUnprocessablePatch},
+		{"null description", MergePatch, `{"description": null}`, ErrUnprocessablePatch},
+		{"removed stock", JSONPatch, `[{"op": "remove", "path": "/stock"}]`, ErrUnprocessablePatch},
+		{"replaced with null", JSONPatch, `[{"op": "replace", "path": "/price", "value": null}]`, ErrUnprocessablePatch},
+	}
+
+	for _, tt := range tests {
+		result, err := presenter.PatchProduct(ctx, 1, tt.format, []byte(tt.patch), 0, validate)
+
+		assert.ErrorIs(t, err, tt.expected, tt.name)
+		assert.Nil(t, result, tt.name)
+	}
+
+	// A patch that produces an invalid product
+	result, err := presenter.PatchProduct(ctx, 1, MergePatch, []byte(`{"price": -1}`), 0, validators.NewValidator().Validate)
+	assert.ErrorIs(t, err, ErrUnprocessablePatch)
+	var validationErr *validators.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything)
+}
+
+func TestProductPresenter_AdjustStock(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	adjusted := &models.Product{ID: 1, Name: "Product", Price: 10, Stock: 7, Version: 2}
+	mockRepo.On("AdjustStock", mock.Anything, uint(1), -3, "order #1001").Return(adjusted, nil)
+	mockRepo.On("AdjustStock", mock.Anything, uint(1), -20, "order #1002").Return(nil, ErrInsufficientStock)
+
+	ctx := context.Background()
+	result, err := presenter.AdjustStock(ctx, 1, models.StockAdjustmentRequest{Delta: -3, Reason: "order #1001"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, result.Stock)
+	assert.Equal(t, uint(2), result.Version)
+
+	result, err = presenter.AdjustStock(ctx, 1, models.StockAdjustmentRequest{Delta: -20, Reason: "order #1002"})
+
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Nil(t, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_WaitForWrites(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	release := make(chan time.Time)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).WaitUntil(release)
+
+	// The request gives up but the repository ignores the cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer waitCancel()
+	assert.ErrorIs(t, presenter.Wait(waitCtx), context.DeadlineExceeded)
+	assert.Equal(t, 1, presenter.InFlight())
+
+	close(release)
+	assert.NoError(t, presenter.Wait(context.Background()))
+	assert.Equal(t, 0, presenter.InFlight())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_CancelAbortsWrite(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	// The write blocks until its context is cancelled
+	started := make(chan struct{})
+	var repoCtx context.Context
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(context.Canceled).Run(func(args mock.Arguments) {
+		repoCtx = args.Get(0).(context.Context)
+		close(started)
+		<-repoCtx.Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, presenter.Wait(context.Background()))
+	assert.Equal(t, 0, presenter.InFlight())
+	assert.ErrorIs(t, repoCtx.Err(), context.Canceled)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductPresenter_TimeoutAbortsWrite(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 10*time.Millisecond, metrics.Nop{})
+
+	// The update blocks until its context is cancelled
+	var repoCtx context.Context
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(&models.Product{ID: 1, Version: 1}, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Product")).Return(context.Canceled).Run(func(args mock.Arguments) {
+		repoCtx = args.Get(0).(context.Context)
+		<-repoCtx.Done()
+	})
+
+	_, err := presenter.UpdateProduct(context.Background(), 1, models.ProductRequest{Name: "Test Product", Price: 1}, 0)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	assert.NoError(t, presenter.Wait(context.Background()))
+	assert.Equal(t, 0, presenter.InFlight())
+	assert.ErrorIs(t, repoCtx.Err(), context.Canceled)
+	mockRepo.AssertExpectations(t)
+}
+
+// RecordingMetrics is a metrics.Recorder that keeps presenter measurements
+type RecordingMetrics struct {
+	metrics.Nop
+	mu         sync.Mutex
+	operations []string
+	inFlight   int
+}
+
+func (m *RecordingMetrics) ObserveOperation(presenter, operation string, outcome metrics.Outcome, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.operations = append(m.operations, presenter+" "+operation+" "+string(outcome))
+}
+
+func (m *RecordingMetrics) AddInFlight(presenter string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+}
+
+func TestProductPresenter_Metrics(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	recorder := &RecordingMetrics{}
+	presenter := NewProductPresenter(mockRepo, 20*time.Millisecond, recorder)
+
+	release := make(chan time.Time)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Once()
+	mockRepo.On("AdjustStock", mock.Anything, uint(1), -5, "").Return((*models.Product)(nil), ErrInsufficientStock).Once()
+	mockRepo.On("GetByID", mock.Anything, uint(2)).Return(&models.Product{ID: 2}, nil).WaitUntil(release)
+
+	ctx := context.Background()
+	_, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 1})
+	assert.NoError(t, err)
+
+	_, err = presenter.AdjustStock(ctx, 1, models.StockAdjustmentRequest{Delta: -5})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	_, err = presenter.UpdateProduct(ctx, 2, models.ProductRequest{Name: "Test Product", Price: 1}, 0)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = presenter.UpdateProduct(canceled, 2, models.ProductRequest{Name: "Test Product", Price: 1}, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The timed out and cancelled writes are still running
+	recorder.mu.Lock()
+	assert.Equal(t, 2, recorder.inFlight)
+	recorder.mu.Unlock()
+
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Twice()
+	close(release)
+	assert.NoError(t, presenter.Wait(ctx))
+
+	// Bulk workers are counted while they run
+	var workers int
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil).Once().Run(func(mock.Arguments) {
+		recorder.mu.Lock()
+		workers = recorder.inFlight
+		recorder.mu.Unlock()
+	})
+	bulk := NewBulkPresenter(mockRepo, 2, recorder)
+	ops := []models.BulkOperation{{Op: models.BulkCreate, Product: &models.ProductRequest{Name: "Test Product", Price: 1}}}
+	_, err = bulk.ProcessBulk(ctx, models.BulkRequest{Operations: ops}, func(interface{}) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, workers)
+
+	_, err = bulk.ProcessBulk(canceled, models.BulkRequest{}, func(interface{}) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, []string{
+		"product create success",
+		"product adjust_stock error",
+		"product update timeout",
+		"product update canceled",
+		"bulk process success",
+		"bulk process canceled",
+	}, recorder.operations)
+	assert.Equal(t, 0, recorder.inFlight)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,161 @@
+package presenters
+
+import (
+	"context"
+	"log"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
+	"time"
+)
+
+// ReservationPresenter interface for stock reservation business logic
+type ReservationPresenter interface {
+	CreateReservation(ctx context.Context, productID uint, req models.ReservationRequest) (*models.ReservationResponse, error)
+	GetReservation(ctx context.Context, id uint) (*models.ReservationResponse, error)
+	ConfirmReservation(ctx context.Context, id uint) (*models.ReservationResponse, error)
+	ReleaseReservation(ctx context.Context, id uint) (*models.ReservationResponse, error)
+	ExpireReservations(ctx context.Context) (int64, error)
+	RunExpiry(ctx context.Context, interval time.Duration)
+	Wait(ctx context.Context) error
+	InFlight() int
+}
+
+// ErrReservationNotActive is returned when a reservation is no longer holding stock
+var ErrReservationNotActive = repositories.ErrReservationNotActive
+
+// reservationPresenter implements ReservationPresenter
+type reservationPresenter struct {
+	background
+	reservationRepo repositories.ReservationRepository
+	defaultTTL      time.Duration
+	timeout         time.Duration
+}
+
+// NewReservationPresenter creates a new reservation presenter. Holds that do
+// not ask for a TTL expire after defaultTTL, operations that do not finish
+// within timeout fail with ErrTimeout, and every operation is recorded with
+// recorder.
+func NewReservationPresenter(reservationRepo repositories.ReservationRepository, defaultTTL, timeout time.Duration, recorder metrics.Recorder) ReservationPresenter {
+	return &reservationPresenter{
+		background:      background{name: "reservation", metrics: recorder},
+		reservationRepo: reservationRepo,
+		defaultTTL:      defaultTTL,
+		timeout:         timeout,
+	}
+}
+
+// CreateReservation holds stock of a product using goroutine
+func (p *reservationPresenter) CreateReservation(ctx context.Context, productID uint, req models.ReservationRequest) (*models.ReservationResponse, error) {
+	ttl := p.defaultTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	reservation := &models.Reservation{
+		ProductID: productID,
+		Quantity:  req.Quantity,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	return p.run(ctx, "create", func(ctx context.Context) (*models.Reservation, error) {
+		if err := p.reservationRepo.Create(ctx, reservation); err != nil {
+			return nil, err
+		}
+		return reservation, nil
+	})
+}
+
+// GetReservation gets a reservation by ID
+func (p *reservationPresenter) GetReservation(ctx context.Context, id uint) (_ *models.ReservationResponse, err error) {
+	ctx, end := p.start(ctx, "get")
+	defer end(&err)
+
+	reservation, err := p.reservationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := reservation.ToResponse()
+	return &response, nil
+}
+
+// ConfirmReservation turns a hold into a stock decrement using goroutine
+func (p *reservationPresenter) ConfirmReservation(ctx context.Context, id uint) (*models.ReservationResponse, error) {
+	return p.run(ctx, "confirm", func(ctx context.Context) (*models.Reservation, error) {
+		return p.reservationRepo.Confirm(ctx, id)
+	})
+}
+
+// ReleaseReservation gives held stock back using goroutine
+func (p *reservationPresenter) ReleaseReservation(ctx context.Context, id uint) (*models.ReservationResponse, error) {
+	return p.run(ctx, "release", func(ctx context.Context) (*models.Reservation, error) {
+		return p.reservationRepo.Release(ctx, id)
+	})
+}
+
+// ExpireReservations expires every active hold past its expiry time
+func (p *reservationPresenter) ExpireReservations(ctx context.Context) (_ int64, err error) {
+	ctx, end := p.start(ctx, "expire")
+	defer end(&err)
+
+	return p.reservationRepo.ExpireStale(ctx, time.Now())
+}
+
+// RunExpiry expires stale holds every interval until ctx is cancelled
+func (p *reservationPresenter) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := p.ExpireReservations(ctx)
+			if err != nil {
+				log.Println("Failed to expire reservations:", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d reservations", expired)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run executes a reservation operation in a goroutine and waits for its
+// result with timeout. op is cancelled if the result is not waited for.
+func (p *reservationPresenter) run(ctx context.Context, operation string, op func(ctx context.Context) (*models.Reservation, error)) (_ *models.ReservationResponse, err error) {
+	ctx, end := p.start(ctx, operation)
+	defer end(&err)
+
+	// Channel to receive result from goroutine
+	resultChan := make(chan struct {
+		reservation *models.Reservation
+		err         error
+	}, 1)
+
+	// Execute operation in goroutine
+	p.spawn(ctx, operation, func(ctx context.Context) {
+		reservation, err := op(ctx)
+		resultChan <- struct {
+			reservation *models.Reservation
+			err         error
+		}{reservation: reservation, err: err}
+	})
+
+	// Wait for result with timeout
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		response := result.reservation.ToResponse()
+		return &response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.timeout):
+		return nil, ErrTimeout
+	}
+}
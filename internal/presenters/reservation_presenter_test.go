@@ -0,0 +1,150 @@
+package presenters
+
+import (
+	"context"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReservationRepository is a mock implementation of ReservationRepository
+type MockReservationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	args := m.Called(ctx, reservation)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) GetByID(ctx context.Context, id uint) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	reservation, _ := args.Get(0).(*models.Reservation)
+	return reservation, args.Error(1)
+}
+
+func (m *MockReservationRepository) Confirm(ctx context.Context, id uint) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	reservation, _ := args.Get(0).(*models.Reservation)
+	return reservation, args.Error(1)
+}
+
+func (m *MockReservationRepository) Release(ctx context.Context, id uint) (*models.Reservation, error) {
+	args := m.Called(ctx, id)
+	reservation, _ := args.Get(0).(*models.Reservation)
+	return reservation, args.Error(1)
+}
+
+func (m *MockReservationRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestReservationPresenter_CreateReservation(t *testing.T) {
+	mockRepo := new(MockReservationRepository)
+	presenter := NewReservationPresenter(mockRepo, 10*time.Minute, 30*time.Second, metrics.Nop{})
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Reservation")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.Reservation)
+		arg.ID = 1
+		arg.Status = models.ReservationActive
+	})
+
+	ctx := context.Background()
+	before := time.Now()
+	result, err := presenter.CreateReservation(ctx, 7, models.ReservationRequest{Quantity: 2, TTLSeconds: 60})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), result.ProductID)
+	assert.Equal(t, 2, result.Quantity)
+	assert.Equal(t, models.ReservationActive, result.Status)
+	assert.WithinDuration(t, before.Add(time.Minute), result.ExpiresAt, time.Second)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReservationPresenter_CreateReservationDefaultTTL(t *testing.T) {
+	mockRepo := new(MockReservationRepository)
+	presenter := NewReservationPresenter(mockRepo, 10*time.Minute, 30*time.Second, metrics.Nop{})
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Reservation")).Return(nil)
+
+	ctx := context.Background()
+	before := time.Now()
+	result, err := presenter.CreateReservation(ctx, 7, models.ReservationRequest{Quantity: 1})
+
+	assert.NoError(t, err)
+	assert.WithinDuration(t, before.Add(10*time.Minute), result.ExpiresAt, time.Second)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReservationPresenter_CreateReservationInsufficientStock(t *testing.T) {
+	mockRepo := new(MockReservationRepository)
+	presenter := NewReservationPresenter(mockRepo, 10*time.Minute, 30*time.Second, metrics.Nop{})
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Reservation")).Return(ErrInsufficientStock)
+
+	ctx := context.Background()
+	result, err := presenter.CreateReservation(ctx, 7, models.ReservationRequest{Quantity: 100})
+
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Nil(t, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReservationPresenter_ConfirmReservation(t *testing.T) {
+	mockRepo := new(MockReservationRepository)
+	presenter := NewReservationPresenter(mockRepo, 10*time.Minute, 30*time.Second, metrics.Nop{})
+
+	confirmed := &models.Reservation{ID: 1, ProductID: 7, Quantity: 2, Status: models.ReservationConfirmed}
+	mockRepo.On("Confirm", mock.Anything, uint(1)).Return(confirmed, nil)
+	mockRepo.On("Confirm", mock.Anything, uint(2)).Return(nil, ErrReservationNotActive)
+
+	ctx := context.Background()
+	result, err := presenter.ConfirmReservation(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationConfirmed, result.Status)
+
+	result, err = presenter.ConfirmReservation(ctx, 2)
+
+	assert.ErrorIs(t, err, ErrReservationNotActive)
+	assert.Nil(t, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReservationPresenter_RunExpiry(t *testing.T) {
+	mockRepo := new(MockReservationRepository)
+	presenter := NewReservationPresenter(mockRepo, 10*time.Minute, 30*time.Second, metrics.Nop{})
+
+	expired := make(chan struct{}, 1)
+	mockRepo.On("ExpireStale", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(3), nil).Run(func(args mock.Arguments) {
+		select {
+		case expired <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		presenter.RunExpiry(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected stale reservations to be expired")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected expiry loop to stop when the context is cancelled")
+	}
+}
@@ -2,70 +2,32 @@ package presenters
 
 import (
 	"context"
+	"errors"
+	"simple-goroutine-product/internal/audit"
+	"simple-goroutine-product/internal/metrics"
 	"simple-goroutine-product/internal/models"
+	"simple-goroutine-product/internal/repositories"
 	"testing"
 	"time"
 )
 
-// SimpleProductRepository is a simple mock implementation
-type SimpleProductRepository struct {
-	products []models.Product
-	nextID   uint
+// newMemoryProductRepository creates an empty in-memory product repository
+func newMemoryProductRepository() repositories.ProductRepository {
+	return repositories.NewMemoryProductRepository(repositories.NewMemoryStore())
 }
 
-func NewSimpleProductRepository() *SimpleProductRepository {
-	return &SimpleProductRepository{
-		products: make([]models.Product, 0),
-		nextID:   1,
-	}
-}
-
-func (r *SimpleProductRepository) Create(product *models.Product) error {
-	product.ID = r.nextID
-	product.CreatedAt = time.Now()
-	product.UpdatedAt = time.Now()
-	r.products = append(r.products, *product)
-	r.nextID++
-	return nil
-}
-
-func (r *SimpleProductRepository) GetByID(id uint) (*models.Product, error) {
-	for _, product := range r.products {
-		if product.ID == id {
-			return &product, nil
-		}
-	}
-	return nil, nil
-}
-
-func (r *SimpleProductRepository) GetAll(page, limit int) ([]models.Product, int64, error) {
-	return r.products, int64(len(r.products)), nil
-}
-
-func (r *SimpleProductRepository) Update(product *models.Product) error {
-	for i, p := range r.products {
-		if p.ID == product.ID {
-			product.UpdatedAt = time.Now()
-			r.products[i] = *product
-			return nil
-		}
-	}
-	return nil
-}
-
-func (r *SimpleProductRepository) Delete(id uint) error {
-	for i, product := range r.products {
-		if product.ID == id {
-			r.products = append(r.products[:i], r.products[i+1:]...)
-			return nil
-		}
+// listProducts returns every product in repo in ID order
+func listProducts(t *testing.T, repo repositories.ProductRepository) []models.Product {
+	products, _, err := repo.GetAll(context.Background(), models.ProductQuery{Page: 1, Limit: 1000})
+	if err != nil {
+		t.Fatalf("Failed to list products: %v", err)
 	}
-	return nil
+	return products
 }
 
 func TestSimpleProductPresenter_CreateProduct(t *testing.T) {
-	repo := NewSimpleProductRepository()
-	presenter := NewProductPresenter(repo)
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
 
 	req := models.ProductRequest{
 		Name:        "Test Product",
@@ -95,8 +57,8 @@ func TestSimpleProductPresenter_CreateProduct(t *testing.T) {
 }
 
 func TestSimpleProductPresenter_GetProduct(t *testing.T) {
-	repo := NewSimpleProductRepository()
-	presenter := NewProductPresenter(repo)
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
 
 	// First create a product
 	req := models.ProductRequest{
@@ -129,8 +91,8 @@ func TestSimpleProductPresenter_GetProduct(t *testing.T) {
 }
 
 func TestSimpleProductPresenter_UpdateProduct(t *testing.T) {
-	repo := NewSimpleProductRepository()
-	presenter := NewProductPresenter(repo)
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
 
 	// First create a product
 	createReq := models.ProductRequest{
@@ -154,7 +116,7 @@ func TestSimpleProductPresenter_UpdateProduct(t *testing.T) {
 		Stock:       10,
 	}
 
-	result, err := presenter.UpdateProduct(ctx, created.ID, updateReq)
+	result, err := presenter.UpdateProduct(ctx, created.ID, updateReq, 0)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -174,8 +136,8 @@ func TestSimpleProductPresenter_UpdateProduct(t *testing.T) {
 }
 
 func TestSimpleProductPresenter_DeleteProduct(t *testing.T) {
-	repo := NewSimpleProductRepository()
-	presenter := NewProductPresenter(repo)
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
 
 	// First create a product
 	req := models.ProductRequest{
@@ -192,7 +154,7 @@ func TestSimpleProductPresenter_DeleteProduct(t *testing.T) {
 	}
 
 	// Delete the product
-	err = presenter.DeleteProduct(ctx, created.ID)
+	err = presenter.DeleteProduct(ctx, created.ID, 0)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -200,13 +162,213 @@ func TestSimpleProductPresenter_DeleteProduct(t *testing.T) {
 
 	// Verify product is deleted
 	result, err := presenter.GetProduct(ctx, created.ID)
-	if err != nil {
-		t.Errorf("Expected no error when getting deleted product, got %v", err)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error when getting deleted product, got %v", err)
 	}
 
-	// For a deleted product, we expect nil result or an empty product
-	// Since our simple repository returns nil for non-existent products
 	if result != nil {
 		t.Error("Expected nil result for deleted product")
 	}
 }
+
+func TestSimpleProductPresenter_UpdateProductStaleVersion(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:  "Original Product",
+		Price: 50.00,
+	}
+
+	ctx := context.Background()
+	created, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	// The first update moves the product to version 2
+	updateReq := models.ProductRequest{
+		Name:  "Updated Product",
+		Price: 99.99,
+	}
+
+	updated, err := presenter.UpdateProduct(ctx, created.ID, updateReq, created.Version)
+	if err != nil {
+		t.Fatalf("Failed to update product: %v", err)
+	}
+
+	if updated.Version != created.Version+1 {
+		t.Errorf("Expected version %d, got %d", created.Version+1, updated.Version)
+	}
+
+	// A second update based on the original version must be rejected
+	_, err = presenter.UpdateProduct(ctx, created.ID, updateReq, created.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected version conflict, got %v", err)
+	}
+
+	err = presenter.DeleteProduct(ctx, created.ID, created.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected version conflict, got %v", err)
+	}
+}
+
+func TestSimpleProductPresenter_PatchProduct(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
+
+	// First create a product
+	createReq := models.ProductRequest{
+		Name:        "Original Product",
+		Description: "Original Description",
+		Price:       50.00,
+		Stock:       5,
+	}
+
+	ctx := context.Background()
+	created, err := presenter.CreateProduct(ctx, createReq)
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	validate := func(interface{}) error { return nil }
+
+	// JSON Patch that checks the stock before decrementing it
+	patch := []byte(`[{"op": "test", "path": "/stock", "value": 5}, {"op": "replace", "path": "/stock", "value": 4}]`)
+	result, err := presenter.PatchProduct(ctx, created.ID, JSONPatch, patch, 0, validate)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Stock != 4 {
+		t.Errorf("Expected stock %d, got %d", 4, result.Stock)
+	}
+
+	if result.Name != createReq.Name || result.Description != createReq.Description {
+		t.Error("Expected untouched fields to be preserved")
+	}
+
+	// A stale If-Match version is rejected
+	_, err = presenter.PatchProduct(ctx, created.ID, MergePatch, []byte(`{"stock": 1}`), created.Version, validate)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected version conflict, got %v", err)
+	}
+}
+
+func TestSimpleProductPresenter_RestoreProduct(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
+
+	ctx := context.Background()
+	created, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 99.99, Stock: 10})
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if err := presenter.DeleteProduct(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Failed to delete product: %v", err)
+	}
+
+	// The deleted product is listed in the trash
+	deleted, total, err := presenter.GetDeletedProducts(ctx, models.ProductQuery{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to list deleted products: %v", err)
+	}
+	if total != 1 || len(deleted) != 1 || deleted[0].ID != created.ID {
+		t.Fatalf("Expected the deleted product in the trash, got %+v", deleted)
+	}
+	if deleted[0].DeletedAt == nil {
+		t.Error("Expected deleted_at to be set for a deleted product")
+	}
+
+	restored, err := presenter.RestoreProduct(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if restored.Version != 2 || restored.DeletedAt != nil {
+		t.Errorf("Expected a live product at version 2, got %+v", restored)
+	}
+
+	if _, err := presenter.GetProduct(ctx, created.ID); err != nil {
+		t.Errorf("Expected the restored product to be found, got %v", err)
+	}
+
+	_, err = presenter.RestoreProduct(ctx, created.ID)
+	if !errors.Is(err, ErrProductNotDeleted) {
+		t.Errorf("Expected ErrProductNotDeleted, got %v", err)
+	}
+}
+
+func TestSimpleProductPresenter_PurgeDeletedProducts(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
+
+	ctx := context.Background()
+	old, _ := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Old", Price: 1})
+	recent, _ := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Recent", Price: 1})
+	live, _ := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Live", Price: 1})
+	presenter.DeleteProduct(ctx, old.ID, 0)
+	time.Sleep(20 * time.Millisecond)
+	presenter.DeleteProduct(ctx, recent.ID, 0)
+
+	purged, err := presenter.PurgeDeletedProducts(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged product, got %d", purged)
+	}
+
+	if _, err := presenter.RestoreProduct(ctx, old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the purged product to be gone, got %v", err)
+	}
+	if _, err := presenter.RestoreProduct(ctx, recent.ID); err != nil {
+		t.Errorf("Expected the recently deleted product to be restorable, got %v", err)
+	}
+
+	// Purging removes live products only when asked to
+	if err := presenter.PurgeProduct(ctx, live.ID, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if products := listProducts(t, repo); len(products) != 1 || products[0].ID != recent.ID {
+		t.Errorf("Expected only the restored product to remain, got %+v", products)
+	}
+}
+
+func TestSimpleProductPresenter_GetProductHistory(t *testing.T) {
+	repo := newMemoryProductRepository()
+	presenter := NewProductPresenter(repo, 30*time.Second, metrics.Nop{})
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{Name: "alice", RequestID: "req-1"})
+	created, err := presenter.CreateProduct(ctx, models.ProductRequest{Name: "Test Product", Price: 99.99, Stock: 10})
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	if _, err := presenter.UpdateProduct(ctx, created.ID, models.ProductRequest{Name: "Test Product", Price: 79.99, Stock: 10}, 0); err != nil {
+		t.Fatalf("Failed to update product: %v", err)
+	}
+
+	history, total, err := presenter.GetProductHistory(ctx, created.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if total != 2 || len(history) != 2 {
+		t.Fatalf("Expected 2 entries, got %d of %d", len(history), total)
+	}
+
+	update := history[0]
+	if update.Action != models.AuditUpdate || update.Actor != "alice" || update.RequestID != "req-1" || update.Version != 2 {
+		t.Errorf("Expected alice's update to version 2 first, got %+v", update)
+	}
+	if len(update.Changes) != 1 || update.Changes[0] != (models.FieldChange{Field: "price", Before: 99.99, After: 79.99}) {
+		t.Errorf("Expected only the price change, got %+v", update.Changes)
+	}
+	if history[1].Action != models.AuditCreate {
+		t.Errorf("Expected the create entry last, got %s", history[1].Action)
+	}
+
+	_, _, err = presenter.GetProductHistory(ctx, created.ID+1, 1, 10)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package presenters
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracer creates the spans of presenter operations
+var tracer = otel.Tracer("simple-goroutine-product/internal/presenters")
+
+// startSpan starts the span of a presenter operation. The returned function
+// ends it and must be deferred with the operation's error result.
+func startSpan(ctx context.Context, name string) (context.Context, func(err *error)) {
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,55 @@
+package presenters
+
+import (
+	"context"
+	"simple-goroutine-product/internal/metrics"
+	"simple-goroutine-product/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProductPresenter_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	mockRepo := new(MockProductRepository)
+	presenter := NewProductPresenter(mockRepo, 30*time.Second, metrics.Nop{})
+
+	// Record the context the repository is called with
+	var repoCtx context.Context
+	mockRepo.On("GetByID", mock.Anything, uint(1)).Return(&models.Product{ID: 1, Version: 1}, nil).Run(func(args mock.Arguments) {
+		repoCtx = args.Get(0).(context.Context)
+	})
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Product")).Return(nil)
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "PUT /api/v1/products/:id")
+	_, err := presenter.UpdateProduct(ctx, 1, models.ProductRequest{Name: "Test Product", Price: 1}, 0)
+	require.NoError(t, err)
+	require.NoError(t, presenter.Wait(context.Background()))
+	request.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Contains(t, spans, "product.update")
+	require.Contains(t, spans, "product.update.goroutine")
+
+	operation := spans["product.update"]
+	goroutine := spans["product.update.goroutine"]
+	assert.Equal(t, request.SpanContext().SpanID(), operation.Parent().SpanID())
+	assert.Equal(t, operation.SpanContext().SpanID(), goroutine.Parent().SpanID())
+
+	// The repository runs in the goroutine's span
+	assert.Equal(t, goroutine.SpanContext().SpanID(), trace.SpanContextFromContext(repoCtx).SpanID())
+	mockRepo.AssertExpectations(t)
+}
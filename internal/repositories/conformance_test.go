@@ -0,0 +1,723 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"simple-goroutine-product/internal/audit"
+	"simple-goroutine-product/internal/config"
+	"simple-goroutine-product/internal/database"
+	"simple-goroutine-product/internal/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// repositoryFactory opens empty product and reservation repositories that
+// share one store
+type repositoryFactory func(t *testing.T) (ProductRepository, ReservationRepository)
+
+func TestMemoryRepositories(t *testing.T) {
+	testRepositories(t, func(t *testing.T) (ProductRepository, ReservationRepository) {
+		store := NewMemoryStore()
+		return NewMemoryProductRepository(store), NewMemoryReservationRepository(store)
+	})
+}
+
+func TestSQLiteRepositories(t *testing.T) {
+	testRepositories(t, func(t *testing.T) (ProductRepository, ReservationRepository) {
+		cfg := config.Default().Database
+		cfg.Driver = "sqlite"
+		cfg.Path = ":memory:"
+
+		db, err := database.ConnectDatabase(context.Background(), cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { database.CloseDatabase(db) })
+		require.NoError(t, database.Migrate(context.Background(), db))
+
+		return NewProductRepository(db), NewReservationRepository(db)
+	})
+}
+
+// TestPostgresRepositories runs against the database named by
+// TEST_POSTGRES_DSN, emptying its tables before every test
+func TestPostgresRepositories(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	testRepositories(t, func(t *testing.T) (ProductRepository, ReservationRepository) {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		require.NoError(t, err)
+		t.Cleanup(func() { database.CloseDatabase(db) })
+		require.NoError(t, database.Migrate(context.Background(), db))
+		require.NoError(t, db.Exec("TRUNCATE products, reservations, product_audit_entries RESTART IDENTITY").Error)
+
+		return NewProductRepository(db), NewReservationRepository(db)
+	})
+}
+
+// jsonRoundTrip normalizes field changes to what the API returns, so that
+// values read back from a database compare equal to stored ones
+func jsonRoundTrip(t *testing.T, changes models.FieldChanges) models.FieldChanges {
+	data, err := json.Marshal(changes)
+	require.NoError(t, err)
+	var decoded models.FieldChanges
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+// testRepositories is the behaviour every ProductRepository and
+// ReservationRepository implementation must share
+func testRepositories(t *testing.T, open repositoryFactory) {
+	ctx := context.Background()
+
+	// create stores a product for a test
+	create := func(t *testing.T, repo ProductRepository, name string, price float64, stock int) *models.Product {
+		product := &models.Product{Name: name, Price: price, Stock: stock, Version: 1}
+		require.NoError(t, repo.Create(ctx, product))
+		return product
+	}
+
+	t.Run("CreateAndGetByID", func(t *testing.T) {
+		repo, _ := open(t)
+
+		product := create(t, repo, "Keyboard", 49.9, 10)
+		assert.NotZero(t, product.ID)
+		assert.Equal(t, uint(1), product.Version)
+		assert.False(t, product.CreatedAt.IsZero())
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Keyboard", found.Name)
+		assert.Equal(t, 49.9, found.Price)
+		assert.Equal(t, 10, found.Stock)
+		assert.Equal(t, 0, found.Reserved)
+
+		missing, err := repo.GetByID(ctx, product.ID+100)
+		assert.Nil(t, missing)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		product.Name = "Mechanical keyboard"
+		require.NoError(t, repo.Update(ctx, product))
+		assert.Equal(t, uint(2), product.Version)
+
+		stale := *product
+		stale.Version = 1
+		assert.ErrorIs(t, repo.Update(ctx, &stale), ErrVersionConflict)
+		assert.Equal(t, uint(1), stale.Version)
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Mechanical keyboard", found.Name)
+		assert.Equal(t, uint(2), found.Version)
+
+		gone := &models.Product{ID: product.ID + 100, Name: "Ghost", Version: 1}
+		assert.ErrorIs(t, repo.Update(ctx, gone), ErrProductNotFound)
+	})
+
+	t.Run("UpdateFieldsWritesOnlyTheGivenFields", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		product.Name = "Mechanical keyboard"
+		product.Price = 0
+		product.Stock = 0
+		require.NoError(t, repo.UpdateFields(ctx, product, "Name", "Stock"))
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Mechanical keyboard", found.Name)
+		assert.Equal(t, 49.9, found.Price)
+		assert.Equal(t, 0, found.Stock)
+		assert.Equal(t, uint(2), found.Version)
+	})
+
+	t.Run("AdjustStock", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		adjusted, err := repo.AdjustStock(ctx, product.ID, -4, "recount")
+		require.NoError(t, err)
+		assert.Equal(t, 6, adjusted.Stock)
+		assert.Equal(t, uint(2), adjusted.Version)
+
+		_, err = repo.AdjustStock(ctx, product.ID, -7, "recount")
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+
+		_, err = repo.AdjustStock(ctx, product.ID+100, 1, "recount")
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("AdjustStockConcurrently", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 0)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.AdjustStock(ctx, product.ID, 1, "recount")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 20, found.Stock)
+		assert.Equal(t, uint(21), found.Version)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+		create(t, repo, "Mouse", 19.5, 5)
+
+		assert.ErrorIs(t, repo.Delete(ctx, product.ID, 2), ErrVersionConflict)
+		require.NoError(t, repo.Delete(ctx, product.ID, 1))
+
+		_, err := repo.GetByID(ctx, product.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.ErrorIs(t, repo.Delete(ctx, product.ID, 0), ErrProductNotFound)
+		assert.ErrorIs(t, repo.Update(ctx, product), ErrProductNotFound)
+
+		products, total, err := repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, "Mouse", products[0].Name)
+	})
+
+	t.Run("TrashAndRestore", func(t *testing.T) {
+		repo, reservations := open(t)
+		keyboard := create(t, repo, "Keyboard", 49.9, 10)
+		mouse := create(t, repo, "Mouse", 19.5, 5)
+		create(t, repo, "Monitor", 199, 2)
+		reservation := &models.Reservation{ProductID: keyboard.ID, Quantity: 3, ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, reservations.Create(ctx, reservation))
+		require.NoError(t, repo.Delete(ctx, keyboard.ID, 0))
+		require.NoError(t, repo.Delete(ctx, mouse.ID, 0))
+
+		deleted, total, err := repo.GetDeleted(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		require.Len(t, deleted, 2)
+		assert.Equal(t, []string{"Mouse", "Keyboard"}, []string{deleted[0].Name, deleted[1].Name})
+		assert.True(t, deleted[0].DeletedAt.Valid)
+		assert.Equal(t, 0, deleted[0].Reserved)
+		assert.Equal(t, 3, deleted[1].Reserved)
+
+		deleted, total, err = repo.GetDeleted(ctx, models.ProductQuery{Search: "key", Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, keyboard.ID, deleted[0].ID)
+
+		restored, err := repo.Restore(ctx, keyboard.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), restored.Version)
+		assert.False(t, restored.DeletedAt.Valid)
+
+		found, err := repo.GetByID(ctx, keyboard.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Keyboard", found.Name)
+
+		_, err = repo.Restore(ctx, keyboard.ID)
+		assert.ErrorIs(t, err, ErrProductNotDeleted)
+		_, err = repo.Restore(ctx, mouse.ID+100)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+
+		_, total, err = repo.GetDeleted(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+	})
+
+	t.Run("HardDelete", func(t *testing.T) {
+		repo, reservations := open(t)
+		keyboard := create(t, repo, "Keyboard", 49.9, 10)
+		mouse := create(t, repo, "Mouse", 19.5, 5)
+		reservation := &models.Reservation{ProductID: keyboard.ID, Quantity: 1, ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, reservations.Create(ctx, reservation))
+		require.NoError(t, repo.Delete(ctx, mouse.ID, 0))
+
+		assert.ErrorIs(t, repo.HardDelete(ctx, keyboard.ID, 2), ErrVersionConflict)
+		require.NoError(t, repo.HardDelete(ctx, keyboard.ID, 1))
+		require.NoError(t, repo.HardDelete(ctx, mouse.ID, 0))
+
+		_, err := repo.GetByID(ctx, keyboard.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		_, err = reservations.GetByID(ctx, reservation.ID)
+		assert.ErrorIs(t, err, ErrReservationNotFound)
+		_, err = repo.Restore(ctx, mouse.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		assert.ErrorIs(t, repo.HardDelete(ctx, keyboard.ID, 0), ErrProductNotFound)
+		assert.ErrorIs(t, repo.HardDelete(ctx, keyboard.ID, 1), ErrProductNotFound)
+	})
+
+	t.Run("PurgeDeleted", func(t *testing.T) {
+		repo, _ := open(t)
+		keyboard := create(t, repo, "Keyboard", 49.9, 10)
+		mouse := create(t, repo, "Mouse", 19.5, 5)
+		require.NoError(t, repo.Delete(ctx, keyboard.ID, 0))
+
+		purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		purged, err = repo.PurgeDeleted(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = repo.Restore(ctx, keyboard.ID)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		_, err = repo.GetByID(ctx, mouse.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("PurgeDeletedInBatches", func(t *testing.T) {
+		defer func(size int) { purgeBatchSize = size }(purgeBatchSize)
+		purgeBatchSize = 2
+
+		repo, _ := open(t)
+		for i := 0; i < 5; i++ {
+			product := create(t, repo, fmt.Sprintf("Product %d", i), 1, 1)
+			require.NoError(t, repo.Delete(ctx, product.ID, 0))
+		}
+
+		purged, err := repo.PurgeDeleted(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), purged)
+
+		_, total, err := repo.GetDeleted(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Zero(t, total)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		repo, reservations := open(t)
+		ctx := audit.WithActor(ctx, audit.Actor{Name: "alice", RequestID: "req-1"})
+
+		product := &models.Product{Name: "Keyboard", Price: 49.9, Stock: 10, Version: 1}
+		require.NoError(t, repo.Create(ctx, product))
+		product.Price = 59.9
+		require.NoError(t, repo.UpdateFields(ctx, product, "Price"))
+		_, err := repo.AdjustStock(ctx, product.ID, -3, "damaged in transit")
+		require.NoError(t, err)
+		reservation := &models.Reservation{ProductID: product.ID, Quantity: 2, ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, reservations.Create(ctx, reservation))
+		_, err = reservations.Confirm(ctx, reservation.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, product.ID, 0))
+		_, err = repo.Restore(ctx, product.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.HardDelete(context.Background(), product.ID, 0))
+
+		entries, total, err := repo.GetHistory(ctx, product.ID, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), total)
+		require.Len(t, entries, 7)
+
+		actions := make([]models.AuditAction, len(entries))
+		versions := make([]uint, len(entries))
+		for i, entry := range entries {
+			actions[i] = entry.Action
+			versions[i] = entry.Version
+		}
+		assert.Equal(t, []models.AuditAction{
+			models.AuditPurge, models.AuditRestore, models.AuditDelete, models.AuditUpdate,
+			models.AuditUpdate, models.AuditUpdate, models.AuditCreate,
+		}, actions)
+		assert.Equal(t, []uint{5, 5, 4, 4, 3, 2, 1}, versions)
+
+		assert.Equal(t, "system", entries[0].Actor)
+		assert.Equal(t, "alice", entries[1].Actor)
+		assert.Equal(t, "req-1", entries[1].RequestID)
+		assert.WithinDuration(t, time.Now(), entries[1].CreatedAt, time.Minute)
+		assert.Empty(t, entries[2].Changes)
+		assert.Equal(t, models.FieldChanges{{Field: "stock", Before: float64(7), After: float64(5)}}, jsonRoundTrip(t, entries[3].Changes))
+		assert.Equal(t, models.FieldChanges{{Field: "stock", Before: float64(10), After: float64(7)}}, jsonRoundTrip(t, entries[4].Changes))
+		assert.Equal(t, "damaged in transit", entries[4].Reason)
+		assert.Empty(t, entries[3].Reason)
+		assert.Equal(t, models.FieldChanges{{Field: "price", Before: 49.9, After: 59.9}}, jsonRoundTrip(t, entries[5].Changes))
+		assert.Len(t, entries[6].Changes, 4)
+
+		entries, total, err = repo.GetHistory(ctx, product.ID, 2, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), total)
+		require.Len(t, entries, 3)
+		assert.Equal(t, models.AuditUpdate, entries[0].Action)
+
+		_, _, err = repo.GetHistory(ctx, product.ID+100, 1, 10)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("HistoryOfBatchesAndRollbacks", func(t *testing.T) {
+		repo, _ := open(t)
+		keyboard := create(t, repo, "Keyboard", 49.9, 10)
+
+		_, err := repo.UpsertBatch(ctx, []models.Product{
+			{ID: keyboard.ID, Name: "Keyboard", Price: 49.9, Stock: 12},
+			{Name: "Mouse", Price: 19.5, Stock: 5},
+		})
+		require.NoError(t, err)
+
+		entries, _, err := repo.GetHistory(ctx, keyboard.ID, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, uint(2), entries[0].Version)
+		assert.Equal(t, models.FieldChanges{{Field: "stock", Before: float64(10), After: float64(12)}}, jsonRoundTrip(t, entries[0].Changes))
+
+		// Entries are rolled back with the change they describe
+		err = repo.Transaction(ctx, func(repo ProductRepository) error {
+			if _, err := repo.AdjustStock(ctx, keyboard.ID, 1, "recount"); err != nil {
+				return err
+			}
+			return errors.New("abort")
+		})
+		require.Error(t, err)
+
+		_, total, err := repo.GetHistory(ctx, keyboard.ID, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		repo, reservations := open(t)
+		deleted := create(t, repo, "Keyboard", 49.9, 10)
+		require.NoError(t, repo.Delete(ctx, deleted.ID, 0))
+		product := create(t, repo, "Mouse", 19.5, 5)
+		reservation := &models.Reservation{ProductID: product.ID, Quantity: 1, ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, reservations.Create(ctx, reservation))
+
+		require.NoError(t, repo.Truncate(audit.WithActor(ctx, audit.Actor{Name: "alice"})))
+
+		_, total, err := repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), total)
+		_, err = reservations.GetByID(ctx, reservation.ID)
+		assert.ErrorIs(t, err, ErrReservationNotFound)
+
+		// Every product, trashed or not, records who purged it
+		for _, id := range []uint{deleted.ID, product.ID} {
+			entries, _, err := repo.GetHistory(ctx, id, 1, 1)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, models.AuditPurge, entries[0].Action)
+			assert.Equal(t, "alice", entries[0].Actor)
+		}
+	})
+
+	t.Run("GetAllFiltersSortsAndPaginates", func(t *testing.T) {
+		repo, _ := open(t)
+		create(t, repo, "Blue widget", 10, 5)
+		create(t, repo, "red Widget", 30, 0)
+		create(t, repo, "Gadget", 20, 3)
+		create(t, repo, "100% cotton widget", 40, 1)
+
+		names := func(products []models.Product) []string {
+			var names []string
+			for _, product := range products {
+				names = append(names, product.Name)
+			}
+			return names
+		}
+
+		products, total, err := repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10, Search: "WIDGET"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Equal(t, []string{"Blue widget", "red Widget", "100% cotton widget"}, names(products))
+
+		// Wildcards in the search term are matched literally
+		products, _, err = repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10, Search: "%"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"100% cotton widget"}, names(products))
+
+		minPrice, maxPrice, minStock := 15.0, 35.0, 1
+		products, _, err = repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10, MinPrice: &minPrice, MaxPrice: &maxPrice, MinStock: &minStock})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Gadget"}, names(products))
+
+		sort := []models.SortField{{Field: "price", Desc: true}}
+		products, total, err = repo.GetAll(ctx, models.ProductQuery{Page: 2, Limit: 3, Sort: sort})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), total)
+		assert.Equal(t, []string{"Blue widget"}, names(products))
+	})
+
+	t.Run("GetAllByCursor", func(t *testing.T) {
+		repo, _ := open(t)
+		for i, price := range []float64{50, 10, 40, 20, 30} {
+			create(t, repo, string(rune('A'+i)), price, 1)
+		}
+		sort := models.SortField{Field: "price"}
+		prices := func(products []models.Product) []float64 {
+			var prices []float64
+			for _, product := range products {
+				prices = append(prices, product.Price)
+			}
+			return prices
+		}
+
+		first, hasMore, err := repo.GetAllByCursor(ctx, models.ProductQuery{Limit: 2, Sort: []models.SortField{sort}})
+		require.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Equal(t, []float64{10, 20}, prices(first))
+
+		next := models.NewCursor(sort, &first[1], false)
+		second, hasMore, err := repo.GetAllByCursor(ctx, models.ProductQuery{Limit: 2, Cursor: &next})
+		require.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Equal(t, []float64{30, 40}, prices(second))
+
+		prev := models.NewCursor(sort, &second[0], true)
+		back, hasMore, err := repo.GetAllByCursor(ctx, models.ProductQuery{Limit: 2, Cursor: &prev})
+		require.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Equal(t, []float64{10, 20}, prices(back))
+
+		last := models.NewCursor(sort, &second[1], false)
+		third, hasMore, err := repo.GetAllByCursor(ctx, models.ProductQuery{Limit: 2, Cursor: &last})
+		require.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Equal(t, []float64{50}, prices(third))
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		repo, _ := open(t)
+		create(t, repo, "Keyboard", 49.9, 10)
+		create(t, repo, "Mouse", 19.5, 5)
+		create(t, repo, "Monitor", 199, 2)
+
+		var names []string
+		err := repo.Stream(ctx, models.ProductQuery{Sort: []models.SortField{{Field: "price"}}}, func(product *models.Product) error {
+			names = append(names, product.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Mouse", "Keyboard", "Monitor"}, names)
+
+		stop := errors.New("stop")
+		calls := 0
+		err = repo.Stream(ctx, models.ProductQuery{}, func(product *models.Product) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("UpsertBatch", func(t *testing.T) {
+		repo, _ := open(t)
+		existing := create(t, repo, "Keyboard", 49.9, 10)
+
+		rejected, err := repo.UpsertBatch(ctx, []models.Product{
+			{ID: existing.ID, Name: "Mechanical keyboard", Price: 59.9, Stock: 8},
+			{Name: "Mouse", Price: 19.5, Stock: 5},
+			{ID: existing.ID + 100, Name: "Ghost", Price: 1},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[int]error{2: ErrProductNotFound}, rejected)
+
+		updated, err := repo.GetByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Mechanical keyboard", updated.Name)
+		assert.Equal(t, 8, updated.Stock)
+		assert.Equal(t, uint(2), updated.Version)
+
+		products, total, err := repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Equal(t, "Mouse", products[1].Name)
+		assert.Equal(t, uint(1), products[1].Version)
+	})
+
+	t.Run("UpsertBatchDuplicateIDs", func(t *testing.T) {
+		repo, _ := open(t)
+		existing := create(t, repo, "Keyboard", 49.9, 10)
+
+		rejected, err := repo.UpsertBatch(ctx, []models.Product{
+			{ID: existing.ID, Name: "Keyboard", Price: 59.9, Stock: 10},
+			{ID: existing.ID, Name: "Keyboard", Price: 59.9, Stock: 7},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, rejected)
+
+		updated, err := repo.GetByID(ctx, existing.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 59.9, updated.Price)
+		assert.Equal(t, 7, updated.Stock)
+		assert.Equal(t, uint(3), updated.Version)
+
+		entries, _, err := repo.GetHistory(ctx, existing.ID, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, uint(3), entries[0].Version)
+		assert.Equal(t, models.FieldChanges{{Field: "stock", Before: float64(10), After: float64(7)}}, jsonRoundTrip(t, entries[0].Changes))
+		assert.Equal(t, uint(2), entries[1].Version)
+		assert.Equal(t, models.FieldChanges{{Field: "price", Before: 49.9, After: 59.9}}, jsonRoundTrip(t, entries[1].Changes))
+	})
+
+	t.Run("TransactionRollsBack", func(t *testing.T) {
+		repo, _ := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		failed := errors.New("failed")
+		err := repo.Transaction(ctx, func(tx ProductRepository) error {
+			require.NoError(t, tx.Create(ctx, &models.Product{Name: "Mouse", Price: 19.5, Version: 1}))
+			if _, err := tx.AdjustStock(ctx, product.ID, -3, "recount"); err != nil {
+				return err
+			}
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		products, total, err := repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, 10, products[0].Stock)
+
+		err = repo.Transaction(ctx, func(tx ProductRepository) error {
+			return tx.Create(ctx, &models.Product{Name: "Mouse", Price: 19.5, Version: 1})
+		})
+		require.NoError(t, err)
+
+		_, total, err = repo.GetAll(ctx, models.ProductQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		repo, reservations := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := repo.GetByID(cancelled, product.ID)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, repo.Create(cancelled, &models.Product{Name: "Mouse", Price: 1, Version: 1}), context.Canceled)
+		_, err = reservations.ExpireStale(cancelled, time.Now())
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Reservations", func(t *testing.T) {
+		repo, reservations := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+		hold := func(quantity int, ttl time.Duration) (*models.Reservation, error) {
+			reservation := &models.Reservation{ProductID: product.ID, Quantity: quantity, ExpiresAt: time.Now().Add(ttl)}
+			return reservation, reservations.Create(ctx, reservation)
+		}
+
+		first, err := hold(6, time.Minute)
+		require.NoError(t, err)
+		assert.NotZero(t, first.ID)
+		assert.Equal(t, models.ReservationActive, first.Status)
+
+		_, err = hold(5, time.Minute)
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 6, found.Reserved)
+		assert.Equal(t, 4, found.AvailableStock())
+
+		confirmed, err := reservations.Confirm(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReservationConfirmed, confirmed.Status)
+
+		found, err = repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, found.Stock)
+		assert.Equal(t, 0, found.Reserved)
+
+		_, err = reservations.Confirm(ctx, first.ID)
+		assert.ErrorIs(t, err, ErrReservationNotActive)
+
+		second, err := hold(4, time.Minute)
+		require.NoError(t, err)
+		released, err := reservations.Release(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReservationReleased, released.Status)
+		_, err = reservations.Release(ctx, second.ID)
+		assert.ErrorIs(t, err, ErrReservationNotActive)
+
+		reservation := &models.Reservation{ProductID: product.ID + 100, Quantity: 1, ExpiresAt: time.Now().Add(time.Minute)}
+		assert.ErrorIs(t, reservations.Create(ctx, reservation), ErrProductNotFound)
+
+		_, err = reservations.GetByID(ctx, second.ID+100)
+		assert.ErrorIs(t, err, ErrReservationNotFound)
+	})
+
+	t.Run("StockCannotDropBelowHolds", func(t *testing.T) {
+		repo, reservations := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+		reservation := &models.Reservation{ProductID: product.ID, Quantity: 6, ExpiresAt: time.Now().Add(time.Minute)}
+		require.NoError(t, reservations.Create(ctx, reservation))
+
+		_, err := repo.AdjustStock(ctx, product.ID, -5, "recount")
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		adjusted, err := repo.AdjustStock(ctx, product.ID, -4, "recount")
+		require.NoError(t, err)
+		assert.Equal(t, 0, adjusted.AvailableStock())
+
+		product.Version = adjusted.Version
+		product.Stock = 5
+		assert.ErrorIs(t, repo.UpdateFields(ctx, product, "Stock"), ErrInsufficientStock)
+		assert.Equal(t, adjusted.Version, product.Version)
+
+		rejected, err := repo.UpsertBatch(ctx, []models.Product{{ID: product.ID, Name: "Keyboard", Price: 49.9, Stock: 5}})
+		require.NoError(t, err)
+		assert.Equal(t, map[int]error{0: ErrInsufficientStock}, rejected)
+
+		// Raising the stock is always allowed and the hold can still be confirmed
+		product.Stock = 7
+		require.NoError(t, repo.UpdateFields(ctx, product, "Stock"))
+		_, err = reservations.Confirm(ctx, reservation.ID)
+		require.NoError(t, err)
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, found.Stock)
+		assert.Equal(t, 1, found.AvailableStock())
+	})
+
+	t.Run("ExpireStale", func(t *testing.T) {
+		repo, reservations := open(t)
+		product := create(t, repo, "Keyboard", 49.9, 10)
+
+		for i := 0; i < 2; i++ {
+			reservation := &models.Reservation{ProductID: product.ID, Quantity: 1, ExpiresAt: time.Now().Add(time.Minute)}
+			require.NoError(t, reservations.Create(ctx, reservation))
+		}
+
+		expired, err := reservations.ExpireStale(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), expired)
+
+		expired, err = reservations.ExpireStale(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), expired)
+
+		reservation, err := reservations.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReservationExpired, reservation.Status)
+
+		found, err := repo.GetByID(ctx, product.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, found.Reserved)
+	})
+}
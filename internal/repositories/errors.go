@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"errors"
+	"simple-goroutine-product/internal/apperrors"
+
+	"gorm.io/gorm"
+)
+
+// Domain error kinds shared with the presenters and validators
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = apperrors.ErrNotFound
+	// ErrConflict is returned when a write conflicts with the current state
+	// of a record
+	ErrConflict = apperrors.ErrConflict
+	// ErrValidation is returned when input is rejected before it is written
+	ErrValidation = apperrors.ErrValidation
+	// ErrTimeout is returned when an operation does not finish in time
+	ErrTimeout = apperrors.ErrTimeout
+)
+
+// translateError converts GORM errors into domain errors, reporting a
+// missing record as notFound
+func translateError(err error, notFound error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return err
+}
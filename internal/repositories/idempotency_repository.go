@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"simple-goroutine-product/internal/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository interface for storing responses to idempotent requests
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// idempotencyRepository implements IdempotencyRepository on the database
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository backed by
+// the idempotency_keys table
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve claims record.Key for a new request. An expired record for the
+// same key is replaced. When the key is already held by an unexpired record,
+// nothing is written and that record is returned instead.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	record.StatusCode = 0
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_hash", "status_code", "content_type", "body", "expires_at", "created_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{time.Now()}},
+		}},
+	}).Create(record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyRecord
+	if err := r.db.WithContext(ctx).Where("key = ?", record.Key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of a reserved request
+func (r *idempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	return r.db.WithContext(ctx).Model(&models.IdempotencyRecord{}).
+		Where("key = ?", record.Key).
+		Updates(map[string]interface{}{
+			"status_code":  record.StatusCode,
+			"content_type": record.ContentType,
+			"body":         record.Body,
+		}).Error
+}
+
+// Release gives up a reserved key that has no response yet so the request
+// can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Where("key = ? AND status_code = 0", key).Delete(&models.IdempotencyRecord{}).Error
+}
+
+// DeleteExpired removes every record that expired before now
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// memoryIdempotencyRepository implements IdempotencyRepository in memory
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository creates a new idempotency repository that
+// keeps records in memory. Records are lost on restart and are not shared
+// between instances.
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[string]models.IdempotencyRecord)}
+}
+
+// Reserve claims record.Key for a new request unless an unexpired record
+// already holds it, in which case that record is returned
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, nil
+	}
+
+	record.StatusCode = 0
+	record.CreatedAt = time.Now()
+	r.records[record.Key] = *record
+	return nil, nil
+}
+
+// Complete stores the response of a reserved request
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[record.Key]
+	if !ok {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.Body = append([]byte(nil), record.Body...)
+	r.records[record.Key] = existing
+	return nil
+}
+
+// Release gives up a reserved key that has no response yet
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[key]; ok && !existing.Completed() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+// DeleteExpired removes every record that expired before now
+func (r *memoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,511 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"reflect"
+	"simple-goroutine-product/internal/apperrors"
+	"simple-goroutine-product/internal/models"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryProductRepository implements ProductRepository on a MemoryStore
+type memoryProductRepository struct {
+	memoryAccess
+}
+
+// NewMemoryProductRepository creates a new product repository that keeps
+// products in store. It is safe for concurrent use.
+func NewMemoryProductRepository(store *MemoryStore) ProductRepository {
+	return &memoryProductRepository{memoryAccess{store: store}}
+}
+
+// Create creates a new product and records it in the audit trail
+func (r *memoryProductRepository) Create(ctx context.Context, product *models.Product) error {
+	return r.write(ctx, func() error {
+		if product.ID == 0 {
+			r.store.nextProductID++
+			product.ID = r.store.nextProductID
+		} else if _, exists := r.store.products[product.ID]; exists {
+			return apperrors.New(ErrConflict, "product already exists")
+		} else {
+			r.store.nextProductID = max(r.store.nextProductID, product.ID)
+		}
+
+		now := time.Now()
+		if product.CreatedAt.IsZero() {
+			product.CreatedAt = now
+		}
+		if product.UpdatedAt.IsZero() {
+			product.UpdatedAt = now
+		}
+		if product.Version == 0 {
+			product.Version = 1
+		}
+		product.Reserved = 0
+		r.store.products[product.ID] = *product
+		r.store.record(ctx, newAuditEntry(models.AuditCreate, product, models.DiffProducts(nil, product)))
+		return nil
+	})
+}
+
+// GetByID gets a product by ID
+func (r *memoryProductRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	err := r.read(ctx, func() error {
+		var ok bool
+		if product, ok = r.store.product(id); !ok {
+			return ErrProductNotFound
+		}
+		product.Reserved = r.store.activeHolds()[id]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetAll gets all products matching the query with pagination
+func (r *memoryProductRepository) GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
+	var products []models.Product
+	var total int64
+
+	err := r.read(ctx, func() error {
+		matches := r.find(query, query.Sort)
+		total = int64(len(matches))
+
+		offset := min(max((query.Page-1)*query.Limit, 0), len(matches))
+		end := min(offset+query.Limit, len(matches))
+		products = matches[offset:end]
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// GetAllByCursor gets a page of products after (or before) the query's
+// cursor using keyset pagination. It reports whether more rows exist in the
+// paging direction.
+func (r *memoryProductRepository) GetAllByCursor(ctx context.Context, query models.ProductQuery) ([]models.Product, bool, error) {
+	sort := query.KeysetSort()
+	backward := query.Cursor != nil && query.Cursor.Backward
+
+	// Walk the order in reverse when paging backwards
+	desc := sort.Desc != backward
+	order := []models.SortField{{Field: sort.Field, Desc: desc}, {Field: "id", Desc: desc}}
+
+	var products []models.Product
+	err := r.read(ctx, func() error {
+		for _, product := range r.find(query, order) {
+			if query.Cursor == nil || afterCursor(&product, sort.Field, query.Cursor, desc) {
+				products = append(products, product)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(products) > query.Limit
+	if hasMore {
+		products = products[:query.Limit]
+	}
+
+	if backward {
+		slices.Reverse(products)
+	}
+
+	return products, hasMore, nil
+}
+
+// afterCursor reports whether a product comes after the cursor when walking
+// products by field, in descending order if desc
+func afterCursor(product *models.Product, field string, cursor *models.Cursor, desc bool) bool {
+	c := 0
+	if models.ProductSortColumns[field] != "id" {
+		c = compareSortValues(product.SortValue(field), cursor.Value)
+	}
+	if c == 0 {
+		c = compareSortValues(product.ID, cursor.ID)
+	}
+	if desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// find returns copies of the products matching the query's filters in the
+// given order, with their reserved quantities. Pagination is ignored. The
+// caller must hold the read lock.
+func (r *memoryProductRepository) find(query models.ProductQuery, sort []models.SortField) []models.Product {
+	holds := r.store.activeHolds()
+
+	var products []models.Product
+	for id := range r.store.products {
+		product, ok := r.store.product(id)
+		if !ok || !matchesProductFilters(&product, query) {
+			continue
+		}
+		product.Reserved = holds[id]
+		products = append(products, product)
+	}
+
+	slices.SortFunc(products, func(a, b models.Product) int {
+		return compareProducts(&a, &b, sort)
+	})
+	return products
+}
+
+// Stream calls fn for every product matching the query's filters and sort.
+// Pagination is ignored. The matching products are copied first, so fn may
+// use the repository. Iteration stops at the first error returned by fn.
+func (r *memoryProductRepository) Stream(ctx context.Context, query models.ProductQuery, fn func(product *models.Product) error) error {
+	var products []models.Product
+	err := r.read(ctx, func() error {
+		products = r.find(query, query.Sort)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Reserved = 0
+		if err := fn(&products[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update updates a product if its stored version still matches
+// product.Version, incrementing the version on success
+func (r *memoryProductRepository) Update(ctx context.Context, product *models.Product) error {
+	return r.UpdateFields(ctx, product, "Name", "Description", "Price", "Stock")
+}
+
+// UpdateFields writes only the given fields of a product if its stored
+// version still matches product.Version, incrementing the version on success
+func (r *memoryProductRepository) UpdateFields(ctx context.Context, product *models.Product, fields ...string) error {
+	return r.write(ctx, func() error {
+		stored, ok := r.store.product(product.ID)
+		if !ok {
+			return ErrProductNotFound
+		}
+		if stored.Version != product.Version {
+			return ErrVersionConflict
+		}
+
+		before := stored
+		src := reflect.ValueOf(product).Elem()
+		dst := reflect.ValueOf(&stored).Elem()
+		for _, field := range fields {
+			dst.FieldByName(field).Set(src.FieldByName(field))
+		}
+
+		if stored.Stock < before.Stock && stored.Stock < r.store.activeHolds()[stored.ID] {
+			return ErrInsufficientStock
+		}
+
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		r.store.products[stored.ID] = stored
+		r.store.record(ctx, newAuditEntry(models.AuditUpdate, &stored, models.DiffProducts(&before, &stored)))
+
+		product.Version = stored.Version
+		product.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+// AdjustStock adds delta to a product's stock, refusing any adjustment that
+// would take the stock below zero or below the quantity held by active
+// reservations. The reason is kept on the audit entry.
+func (r *memoryProductRepository) AdjustStock(ctx context.Context, id uint, delta int, reason string) (*models.Product, error) {
+	var product models.Product
+	err := r.write(ctx, func() error {
+		var ok bool
+		if product, ok = r.store.product(id); !ok {
+			return ErrProductNotFound
+		}
+		holds := r.store.activeHolds()[id]
+		if product.Stock+delta < 0 || (delta < 0 && product.Stock+delta < holds) {
+			return ErrInsufficientStock
+		}
+
+		before := product
+		product.Stock += delta
+		product.Version++
+		product.UpdatedAt = time.Now()
+		r.store.products[id] = product
+		entry := newAuditEntry(models.AuditUpdate, &product, models.DiffProducts(&before, &product))
+		entry.Reason = reason
+		r.store.record(ctx, entry)
+
+		product.Reserved = holds
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// UpsertBatch writes a batch of products at once. Products without an ID
+// are inserted and products with one update the existing product. Products
+// that cannot be written are skipped while the rest of the batch is still
+// written. Their errors are returned by index: ErrProductNotFound when the
+// ID does not exist and ErrInsufficientStock when the new stock is below the
+// quantity held by active reservations. A product listed more than once is
+// written in order, so its last row wins and each row gets its own audit
+// entry.
+func (r *memoryProductRepository) UpsertBatch(ctx context.Context, products []models.Product) (map[int]error, error) {
+	rejected := make(map[int]error)
+
+	err := r.write(ctx, func() error {
+		now := time.Now()
+		holds := r.store.activeHolds()
+		for i, product := range products {
+			if product.ID == 0 {
+				r.store.nextProductID++
+				product.ID = r.store.nextProductID
+				product.Version = 1
+				product.CreatedAt = now
+				product.UpdatedAt = now
+				product.Reserved = 0
+				r.store.products[product.ID] = product
+				r.store.record(ctx, newAuditEntry(models.AuditCreate, &product, models.DiffProducts(nil, &product)))
+				continue
+			}
+
+			stored, ok := r.store.product(product.ID)
+			if !ok {
+				rejected[i] = ErrProductNotFound
+				continue
+			}
+			if product.Stock < stored.Stock && product.Stock < holds[stored.ID] {
+				rejected[i] = ErrInsufficientStock
+				continue
+			}
+			before := stored
+			stored.Name = product.Name
+			stored.Description = product.Description
+			stored.Price = product.Price
+			stored.Stock = product.Stock
+			stored.Version++
+			stored.UpdatedAt = now
+			r.store.products[stored.ID] = stored
+			r.store.record(ctx, newAuditEntry(models.AuditUpdate, &stored, models.DiffProducts(&before, &stored)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+// Delete soft deletes a product. A non-zero version makes the delete
+// conditional on the stored version matching.
+func (r *memoryProductRepository) Delete(ctx context.Context, id uint, version uint) error {
+	return r.write(ctx, func() error {
+		product, ok := r.store.product(id)
+		if !ok {
+			return ErrProductNotFound
+		}
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+
+		product.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		r.store.products[id] = product
+		r.store.record(ctx, newAuditEntry(models.AuditDelete, &product, nil))
+		return nil
+	})
+}
+
+// GetDeleted gets the soft deleted products matching the query with
+// pagination. Without a sort the most recently deleted come first.
+func (r *memoryProductRepository) GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
+	var products []models.Product
+	var total int64
+
+	err := r.read(ctx, func() error {
+		var matches []models.Product
+		for _, product := range r.store.products {
+			if product.DeletedAt.Valid && matchesProductFilters(&product, query) {
+				matches = append(matches, product)
+			}
+		}
+
+		slices.SortFunc(matches, func(a, b models.Product) int {
+			if len(query.Sort) > 0 {
+				return compareProducts(&a, &b, query.Sort)
+			}
+			if c := b.DeletedAt.Time.Compare(a.DeletedAt.Time); c != 0 {
+				return c
+			}
+			return cmp.Compare(b.ID, a.ID)
+		})
+		total = int64(len(matches))
+
+		offset := min(max((query.Page-1)*query.Limit, 0), len(matches))
+		end := min(offset+query.Limit, len(matches))
+		products = matches[offset:end]
+
+		// Holds outlive a soft delete, so trashed products can still have some
+		holds := r.store.activeHolds()
+		for i := range products {
+			products[i].Reserved = holds[products[i].ID]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// Restore takes a soft deleted product out of the trash, incrementing its
+// version
+func (r *memoryProductRepository) Restore(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+	err := r.write(ctx, func() error {
+		var ok bool
+		if product, ok = r.store.products[id]; !ok {
+			return ErrProductNotFound
+		}
+		if !product.DeletedAt.Valid {
+			return ErrProductNotDeleted
+		}
+
+		product.DeletedAt = gorm.DeletedAt{}
+		product.Version++
+		product.UpdatedAt = time.Now()
+		r.store.products[id] = product
+		r.store.record(ctx, newAuditEntry(models.AuditRestore, &product, nil))
+		product.Reserved = r.store.activeHolds()[id]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// HardDelete permanently removes a product, whether or not it is in the
+// trash, together with its reservations. A non-zero version makes the delete
+// conditional on the stored version matching. The product's history is kept.
+func (r *memoryProductRepository) HardDelete(ctx context.Context, id uint, version uint) error {
+	return r.write(ctx, func() error {
+		product, ok := r.store.products[id]
+		if !ok {
+			return ErrProductNotFound
+		}
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+
+		r.store.remove(id)
+		r.store.record(ctx, newAuditEntry(models.AuditPurge, &product, nil))
+		return nil
+	})
+}
+
+// PurgeDeleted permanently removes the products soft deleted before the
+// given time together with their reservations, returning how many products
+// were removed. The products' history is kept.
+func (r *memoryProductRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.write(ctx, func() error {
+		for id, product := range r.store.products {
+			if product.DeletedAt.Valid && product.DeletedAt.Time.Before(before) {
+				r.store.remove(id)
+				r.store.record(ctx, newAuditEntry(models.AuditPurge, &product, nil))
+				purged++
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// GetHistory gets a product's audit entries with pagination, newest first.
+// The history of a purged product is still returned.
+func (r *memoryProductRepository) GetHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditEntry, int64, error) {
+	entries := []models.ProductAuditEntry{}
+	var total int64
+
+	err := r.read(ctx, func() error {
+		for i := len(r.store.audit) - 1; i >= 0; i-- {
+			if r.store.audit[i].ProductID == id {
+				entries = append(entries, r.store.audit[i])
+			}
+		}
+		total = int64(len(entries))
+
+		if _, ok := r.store.products[id]; !ok && total == 0 {
+			return ErrProductNotFound
+		}
+
+		offset := min(max((page-1)*limit, 0), len(entries))
+		end := min(offset+limit, len(entries))
+		entries = entries[offset:end]
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Truncate permanently removes every product, soft deleted ones included,
+// together with all reservations. A purge entry is recorded for every
+// product, so the audit trail shows who emptied the catalog. IDs keep
+// counting from where they were.
+func (r *memoryProductRepository) Truncate(ctx context.Context) error {
+	return r.write(ctx, func() error {
+		ids := slices.Sorted(maps.Keys(r.store.products))
+		for _, id := range ids {
+			product := r.store.products[id]
+			r.store.record(ctx, newAuditEntry(models.AuditPurge, &product, nil))
+		}
+
+		r.store.products = make(map[uint]models.Product)
+		r.store.reservations = make(map[uint]models.Reservation)
+		return nil
+	})
+}
+
+// Transaction runs fn with a repository that holds the store's write lock,
+// restoring the store's data if fn returns an error or panics. Concurrent
+// operations wait until fn returns.
+func (r *memoryProductRepository) Transaction(ctx context.Context, fn func(repo ProductRepository) error) error {
+	return r.write(ctx, func() error {
+		snapshot := r.store.snapshot()
+		committed := false
+		defer func() {
+			if !committed {
+				r.store.restore(snapshot)
+			}
+		}()
+
+		if err := fn(&memoryProductRepository{memoryAccess{store: r.store, inTx: true}}); err != nil {
+			return err
+		}
+		committed = true
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"simple-goroutine-product/internal/models"
+	"time"
+)
+
+// memoryReservationRepository implements ReservationRepository on a
+// MemoryStore
+type memoryReservationRepository struct {
+	memoryAccess
+}
+
+// NewMemoryReservationRepository creates a new reservation repository that
+// keeps reservations in store, holding stock of the store's products. It is
+// safe for concurrent use.
+func NewMemoryReservationRepository(store *MemoryStore) ReservationRepository {
+	return &memoryReservationRepository{memoryAccess{store: store}}
+}
+
+// Create holds stock for a reservation if enough of it is available
+func (r *memoryReservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	return r.write(ctx, func() error {
+		product, ok := r.store.product(reservation.ProductID)
+		if !ok {
+			return ErrProductNotFound
+		}
+
+		if product.Stock-r.store.activeHolds()[product.ID] < reservation.Quantity {
+			return ErrInsufficientStock
+		}
+
+		now := time.Now()
+		r.store.nextReservationID++
+		reservation.ID = r.store.nextReservationID
+		reservation.Status = models.ReservationActive
+		reservation.CreatedAt = now
+		reservation.UpdatedAt = now
+		r.store.reservations[reservation.ID] = *reservation
+		return nil
+	})
+}
+
+// GetByID gets a reservation by ID
+func (r *memoryReservationRepository) GetByID(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.read(ctx, func() error {
+		var ok bool
+		if reservation, ok = r.store.reservations[id]; !ok {
+			return ErrReservationNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// Confirm turns an active reservation into a stock decrement, recorded in
+// the product's audit trail
+func (r *memoryReservationRepository) Confirm(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.write(ctx, func() error {
+		var err error
+		if reservation, err = r.active(id); err != nil {
+			return err
+		}
+
+		product, ok := r.store.product(reservation.ProductID)
+		if !ok || product.Stock < reservation.Quantity {
+			return ErrInsufficientStock
+		}
+		before := product
+		product.Stock -= reservation.Quantity
+		product.Version++
+		product.UpdatedAt = time.Now()
+		r.store.products[product.ID] = product
+		r.store.record(ctx, newAuditEntry(models.AuditUpdate, &product, models.DiffProducts(&before, &product)))
+
+		reservation = r.setStatus(reservation, models.ReservationConfirmed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// Release gives the stock held by an active reservation back
+func (r *memoryReservationRepository) Release(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.write(ctx, func() error {
+		var err error
+		if reservation, err = r.active(id); err != nil {
+			return err
+		}
+
+		reservation = r.setStatus(reservation, models.ReservationReleased)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// ExpireStale marks every active reservation that expired before now
+func (r *memoryReservationRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	var expired int64
+	err := r.write(ctx, func() error {
+		for _, reservation := range r.store.reservations {
+			if reservation.Status == models.ReservationActive && !reservation.ExpiresAt.After(now) {
+				r.setStatus(reservation, models.ReservationExpired)
+				expired++
+			}
+		}
+		return nil
+	})
+	return expired, err
+}
+
+// active loads a reservation, failing unless it is still active. A hold
+// past its expiry counts as inactive even before it is swept. The caller
+// must hold the write lock.
+func (r *memoryReservationRepository) active(id uint) (models.Reservation, error) {
+	reservation, ok := r.store.reservations[id]
+	if !ok {
+		return models.Reservation{}, ErrReservationNotFound
+	}
+
+	if reservation.Status != models.ReservationActive || !reservation.ExpiresAt.After(time.Now()) {
+		return models.Reservation{}, ErrReservationNotActive
+	}
+
+	return reservation, nil
+}
+
+// setStatus stores a reservation with a new status and returns it. The
+// caller must hold the write lock.
+func (r *memoryReservationRepository) setStatus(reservation models.Reservation, status models.ReservationStatus) models.Reservation {
+	reservation.Status = status
+	reservation.UpdatedAt = time.Now()
+	r.store.reservations[reservation.ID] = reservation
+	return reservation
+}
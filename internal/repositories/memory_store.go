@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"simple-goroutine-product/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore holds products, their audit trail and reservations in memory
+// for the memory repositories. Repositories created from the same store
+// share its data, so reservations hold the stock of its products. Data is
+// lost on restart.
+type MemoryStore struct {
+	mu                sync.RWMutex
+	products          map[uint]models.Product
+	reservations      map[uint]models.Reservation
+	audit             []models.ProductAuditEntry
+	nextProductID     uint
+	nextReservationID uint
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		products:     make(map[uint]models.Product),
+		reservations: make(map[uint]models.Reservation),
+	}
+}
+
+// memorySnapshot is a copy of a store's data taken to roll a transaction back
+type memorySnapshot struct {
+	products          map[uint]models.Product
+	reservations      map[uint]models.Reservation
+	audit             int
+	nextProductID     uint
+	nextReservationID uint
+}
+
+// snapshot copies the store's data. The caller must hold the write lock.
+func (s *MemoryStore) snapshot() memorySnapshot {
+	return memorySnapshot{
+		products:          maps.Clone(s.products),
+		reservations:      maps.Clone(s.reservations),
+		audit:             len(s.audit),
+		nextProductID:     s.nextProductID,
+		nextReservationID: s.nextReservationID,
+	}
+}
+
+// restore puts back the data of a snapshot. The caller must hold the write
+// lock.
+func (s *MemoryStore) restore(snapshot memorySnapshot) {
+	s.products = snapshot.products
+	s.reservations = snapshot.reservations
+	s.audit = s.audit[:snapshot.audit]
+	s.nextProductID = snapshot.nextProductID
+	s.nextReservationID = snapshot.nextReservationID
+}
+
+// product returns the product with the given ID unless it is missing or
+// soft deleted
+func (s *MemoryStore) product(id uint) (models.Product, bool) {
+	product, ok := s.products[id]
+	if !ok || product.DeletedAt.Valid {
+		return models.Product{}, false
+	}
+	return product, true
+}
+
+// record appends audit entries attributed to the actor in ctx. Entries are
+// only ever appended, so a snapshot remembers how many there were. The
+// caller must hold the write lock.
+func (s *MemoryStore) record(ctx context.Context, entries ...models.ProductAuditEntry) {
+	stampAuditEntries(ctx, entries)
+	for _, entry := range entries {
+		entry.ID = uint(len(s.audit)) + 1
+		s.audit = append(s.audit, entry)
+	}
+}
+
+// remove deletes a product for good along with its reservations. The
+// caller must hold the write lock.
+func (s *MemoryStore) remove(id uint) {
+	delete(s.products, id)
+	for reservationID, reservation := range s.reservations {
+		if reservation.ProductID == id {
+			delete(s.reservations, reservationID)
+		}
+	}
+}
+
+// activeHolds sums the quantity of unexpired active reservations per product
+func (s *MemoryStore) activeHolds() map[uint]int {
+	now := time.Now()
+	holds := make(map[uint]int)
+	for _, reservation := range s.reservations {
+		if reservation.Status == models.ReservationActive && reservation.ExpiresAt.After(now) {
+			holds[reservation.ProductID] += reservation.Quantity
+		}
+	}
+	return holds
+}
+
+// memoryAccess guards access to a MemoryStore. Repositories bound to a
+// transaction already hold the store's write lock and do not lock again.
+type memoryAccess struct {
+	store *MemoryStore
+	inTx  bool
+}
+
+// read runs fn while holding the store's read lock
+func (a memoryAccess) read(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !a.inTx {
+		a.store.mu.RLock()
+		defer a.store.mu.RUnlock()
+	}
+	return fn()
+}
+
+// write runs fn while holding the store's write lock
+func (a memoryAccess) write(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !a.inTx {
+		a.store.mu.Lock()
+		defer a.store.mu.Unlock()
+	}
+	return fn()
+}
+
+// matchesProductFilters reports whether a product passes the query's filters
+func matchesProductFilters(product *models.Product, query models.ProductQuery) bool {
+	if query.Search != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(query.Search)) {
+		return false
+	}
+	if query.MinPrice != nil && product.Price < *query.MinPrice {
+		return false
+	}
+	if query.MaxPrice != nil && product.Price > *query.MaxPrice {
+		return false
+	}
+	if query.MinStock != nil && product.Stock < *query.MinStock {
+		return false
+	}
+	if query.CreatedFrom != nil && product.CreatedAt.Before(*query.CreatedFrom) {
+		return false
+	}
+	if query.CreatedTo != nil && product.CreatedAt.After(*query.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// compareProducts orders two products by the given fields, falling back to
+// ascending id like applyProductSort
+func compareProducts(a, b *models.Product, sort []models.SortField) int {
+	for _, field := range sort {
+		if _, ok := models.ProductSortColumns[field.Field]; !ok {
+			continue
+		}
+		c := compareSortValues(a.SortValue(field.Field), b.SortValue(field.Field))
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// compareSortValues compares two sort key values of the same field
+func compareSortValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case int:
+		return cmp.Compare(a, b.(int))
+	case uint:
+		return cmp.Compare(a, b.(uint))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		return 0
+	}
+}
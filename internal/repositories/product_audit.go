@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"simple-goroutine-product/internal/audit"
+	"simple-goroutine-product/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newAuditEntry describes a change that left product at its current
+// version. The actor and time are filled in when the entry is recorded.
+func newAuditEntry(action models.AuditAction, product *models.Product, changes []models.FieldChange) models.ProductAuditEntry {
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+	return models.ProductAuditEntry{
+		ProductID: product.ID,
+		Action:    action,
+		Changes:   changes,
+		Version:   product.Version,
+	}
+}
+
+// stampAuditEntries attributes entries to the actor in ctx
+func stampAuditEntries(ctx context.Context, entries []models.ProductAuditEntry) {
+	actor := audit.ActorFrom(ctx)
+	now := time.Now()
+	for i := range entries {
+		entries[i].Actor = actor.Name
+		entries[i].RequestID = actor.RequestID
+		entries[i].CreatedAt = now
+	}
+}
+
+// recordAudit writes audit entries in tx, so they are committed or rolled
+// back together with the change they describe
+func recordAudit(ctx context.Context, tx *gorm.DB, entries ...models.ProductAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	stampAuditEntries(ctx, entries)
+	return tx.Create(&entries).Error
+}
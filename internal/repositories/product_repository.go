@@ -1,20 +1,53 @@
 package repositories
 
 import (
+	"context"
+	"simple-goroutine-product/internal/apperrors"
 	"simple-goroutine-product/internal/models"
+	"slices"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ProductRepository interface for product data operations
 type ProductRepository interface {
-	Create(product *models.Product) error
-	GetByID(id uint) (*models.Product, error)
-	GetAll(page, limit int) ([]models.Product, int64, error)
-	Update(product *models.Product) error
-	Delete(id uint) error
+	Create(ctx context.Context, product *models.Product) error
+	GetByID(ctx context.Context, id uint) (*models.Product, error)
+	GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error)
+	GetAllByCursor(ctx context.Context, query models.ProductQuery) ([]models.Product, bool, error)
+	Stream(ctx context.Context, query models.ProductQuery, fn func(product *models.Product) error) error
+	Update(ctx context.Context, product *models.Product) error
+	UpdateFields(ctx context.Context, product *models.Product, fields ...string) error
+	AdjustStock(ctx context.Context, id uint, delta int, reason string) (*models.Product, error)
+	UpsertBatch(ctx context.Context, products []models.Product) (map[int]error, error)
+	Delete(ctx context.Context, id uint, version uint) error
+	GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error)
+	Restore(ctx context.Context, id uint) (*models.Product, error)
+	HardDelete(ctx context.Context, id uint, version uint) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	GetHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditEntry, int64, error)
+	Truncate(ctx context.Context) error
+	Transaction(ctx context.Context, fn func(repo ProductRepository) error) error
 }
 
+// ErrProductNotFound is returned when a product does not exist
+var ErrProductNotFound = apperrors.New(ErrNotFound, "product not found")
+
+// ErrVersionConflict is returned when a conditional write finds that the
+// product was modified since the expected version was read
+var ErrVersionConflict = apperrors.New(ErrConflict, "product version conflict")
+
+// ErrProductNotDeleted is returned when restoring a product that is not in
+// the trash
+var ErrProductNotDeleted = apperrors.New(ErrConflict, "product is not deleted")
+
+// ErrInsufficientStock is returned when a stock change would make the stock
+// negative or take it below the quantity held by active reservations
+var ErrInsufficientStock = apperrors.New(ErrConflict, "insufficient stock")
+
 // productRepository implements ProductRepository
 type productRepository struct {
 	db *gorm.DB
@@ -25,42 +58,660 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
-// Create creates a new product
-func (r *productRepository) Create(product *models.Product) error {
-	return r.db.Create(product).Error
+// Create creates a new product and records it in the audit trail
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, newAuditEntry(models.AuditCreate, product, models.DiffProducts(nil, product)))
+	})
 }
 
 // GetByID gets a product by ID
-func (r *productRepository) GetByID(id uint) (*models.Product, error) {
+func (r *productRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
 	var product models.Product
-	err := r.db.First(&product, id).Error
+	err := r.db.WithContext(ctx).First(&product, id).Error
 	if err != nil {
+		return nil, translateError(err, ErrProductNotFound)
+	}
+	if err := r.loadReserved(ctx, &product); err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
-// GetAll gets all products with pagination
-func (r *productRepository) GetAll(page, limit int) ([]models.Product, int64, error) {
+// GetAll gets all products matching the query with pagination
+func (r *productRepository) GetAll(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
 	var products []models.Product
 	var total int64
 
-	// Count total records
-	r.db.Model(&models.Product{}).Count(&total)
+	// Count total matching records
+	if err := applyProductFilters(r.db.WithContext(ctx).Model(&models.Product{}), query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
 	// Get paginated records
+	offset := (query.Page - 1) * query.Limit
+	db := applyProductSort(applyProductFilters(r.db.WithContext(ctx), query), query.Sort)
+	if err := db.Offset(offset).Limit(query.Limit).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.loadReserved(ctx, productPointers(products)...); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// GetAllByCursor gets a page of products after (or before) the query's
+// cursor using keyset pagination. It reports whether more rows exist in the
+// paging direction and skips counting the total.
+func (r *productRepository) GetAllByCursor(ctx context.Context, query models.ProductQuery) ([]models.Product, bool, error) {
+	var products []models.Product
+
+	sort := query.KeysetSort()
+	column := models.ProductSortColumns[sort.Field]
+	backward := query.Cursor != nil && query.Cursor.Backward
+
+	// Walk the index in reverse when paging backwards
+	desc := sort.Desc != backward
+	cmp, dir := ">", " ASC"
+	if desc {
+		cmp, dir = "<", " DESC"
+	}
+
+	db := applyProductFilters(r.db.WithContext(ctx), query)
+	if query.Cursor != nil {
+		if column == "id" {
+			db = db.Where("id "+cmp+" ?", query.Cursor.ID)
+		} else {
+			db = db.Where("("+column+" "+cmp+" ? OR ("+column+" = ? AND id "+cmp+" ?))",
+				query.Cursor.Value, query.Cursor.Value, query.Cursor.ID)
+		}
+	}
+	if column != "id" {
+		db = db.Order(column + dir)
+	}
+	db = db.Order("id" + dir)
+
+	// Fetch one extra row to find out whether there is another page
+	if err := db.Limit(query.Limit + 1).Find(&products).Error; err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(products) > query.Limit
+	if hasMore {
+		products = products[:query.Limit]
+	}
+
+	if backward {
+		for i, j := 0, len(products)-1; i < j; i, j = i+1, j-1 {
+			products[i], products[j] = products[j], products[i]
+		}
+	}
+
+	if err := r.loadReserved(ctx, productPointers(products)...); err != nil {
+		return nil, false, err
+	}
+
+	return products, hasMore, nil
+}
+
+// loadReserved fills in the quantity held by active reservations for each product
+func (r *productRepository) loadReserved(ctx context.Context, products ...*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	reserved, err := activeHolds(r.db.WithContext(ctx), ids...)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.Reserved = reserved[product.ID]
+	}
+	return nil
+}
+
+// productPointers returns pointers to the elements of products
+func productPointers(products []models.Product) []*models.Product {
+	pointers := make([]*models.Product, len(products))
+	for i := range products {
+		pointers[i] = &products[i]
+	}
+	return pointers
+}
+
+// Stream calls fn for every product matching the query's filters and sort,
+// reading rows one at a time from a database cursor. Pagination is ignored.
+// Iteration stops at the first error returned by fn.
+func (r *productRepository) Stream(ctx context.Context, query models.ProductQuery, fn func(product *models.Product) error) error {
+	db := applyProductSort(applyProductFilters(r.db.WithContext(ctx).Model(&models.Product{}), query), query.Sort)
+
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product models.Product
+		if err := r.db.ScanRows(rows, &product); err != nil {
+			return err
+		}
+		if err := fn(&product); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// applyProductFilters adds the query's filter conditions to db
+func applyProductFilters(db *gorm.DB, query models.ProductQuery) *gorm.DB {
+	if query.Search != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(query.Search))+"%")
+	}
+	if query.MinPrice != nil {
+		db = db.Where("price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("price <= ?", *query.MaxPrice)
+	}
+	if query.MinStock != nil {
+		db = db.Where("stock >= ?", *query.MinStock)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *query.CreatedTo)
+	}
+	return db
+}
+
+// applyProductSort orders db by the given fields, falling back to id so
+// that pages stay stable when sort keys are equal
+func applyProductSort(db *gorm.DB, sort []models.SortField) *gorm.DB {
+	hasID := false
+	for _, field := range sort {
+		column, ok := models.ProductSortColumns[field.Field]
+		if !ok {
+			continue
+		}
+		if column == "id" {
+			hasID = true
+		}
+		if field.Desc {
+			db = db.Order(column + " DESC")
+		} else {
+			db = db.Order(column + " ASC")
+		}
+	}
+	if !hasID {
+		db = db.Order("id ASC")
+	}
+	return db
+}
+
+// escapeLike escapes LIKE wildcards so the search term is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// Update updates a product if its stored version still matches
+// product.Version, incrementing the version on success
+func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
+	return r.UpdateFields(ctx, product, "Name", "Description", "Price", "Stock")
+}
+
+// UpdateFields writes only the given fields of a product if its stored
+// version still matches product.Version, incrementing the version on success
+func (r *productRepository) UpdateFields(ctx context.Context, product *models.Product, fields ...string) error {
+	expected := product.Version
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.Product
+		if err := lockProduct(tx, product.ID, &before); err != nil {
+			return err
+		}
+		if before.Version != expected {
+			return ErrVersionConflict
+		}
+
+		if slices.Contains(fields, "Stock") && product.Stock < before.Stock {
+			if err := checkHolds(tx, product.ID, product.Stock); err != nil {
+				return err
+			}
+		}
+
+		product.Version = expected + 1
+		columns := append(append([]string{}, fields...), "Version", "UpdatedAt")
+		if err := tx.Model(product).Select(columns).Updates(product).Error; err != nil {
+			return err
+		}
+
+		// Fields that were not written keep their stored values
+		var after models.Product
+		if err := tx.First(&after, product.ID).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, newAuditEntry(models.AuditUpdate, &after, models.DiffProducts(&before, &after)))
+	})
+	if err != nil {
+		product.Version = expected
+		return err
+	}
+	return nil
+}
+
+// AdjustStock atomically adds delta to a product's stock, refusing any
+// adjustment that would take the stock below zero or below the quantity held
+// by active reservations. The reason is kept on the audit entry.
+func (r *productRepository) AdjustStock(ctx context.Context, id uint, delta int, reason string) (*models.Product, error) {
+	var product models.Product
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, id, &product); err != nil {
+			return err
+		}
+
+		before := product
+		product.Stock += delta
+		if product.Stock < 0 {
+			return ErrInsufficientStock
+		}
+		if delta < 0 {
+			if err := checkHolds(tx, id, product.Stock); err != nil {
+				return err
+			}
+		}
+
+		product.Version++
+		product.UpdatedAt = time.Now()
+		err := tx.Model(&product).UpdateColumns(map[string]interface{}{
+			"stock":      product.Stock,
+			"version":    product.Version,
+			"updated_at": product.UpdatedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		entry := newAuditEntry(models.AuditUpdate, &product, models.DiffProducts(&before, &product))
+		entry.Reason = reason
+		return recordAudit(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadReserved(ctx, &product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// checkHolds fails with ErrInsufficientStock when stock is below the
+// quantity held by the product's active reservations. The product must be
+// locked so no hold is added in the meantime.
+func checkHolds(tx *gorm.DB, id uint, stock int) error {
+	holds, err := activeHolds(tx, id)
+	if err != nil {
+		return err
+	}
+	if stock < holds[id] {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// UpsertBatch writes a batch of products in a single transaction. Products
+// without an ID are inserted and products with one update the existing row.
+// Products that cannot be written are skipped while the rest of the batch is
+// still written. Their errors are returned by index: ErrProductNotFound when
+// the ID does not exist and ErrInsufficientStock when the new stock is below
+// the quantity held by active reservations. A product listed more than once
+// is written in order, so its last row wins and each row gets its own audit
+// entry.
+func (r *productRepository) UpsertBatch(ctx context.Context, products []models.Product) (map[int]error, error) {
+	rejected := make(map[int]error)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		for _, product := range products {
+			if product.ID != 0 {
+				ids = append(ids, product.ID)
+			}
+		}
+
+		// The stored rows are the "before" side of the audit entries
+		existing := make(map[uint]models.Product, len(ids))
+		holds := make(map[uint]int)
+		if len(ids) > 0 {
+			var found []models.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Find(&found).Error; err != nil {
+				return err
+			}
+			for _, product := range found {
+				existing[product.ID] = product
+			}
+
+			var err error
+			if holds, err = activeHolds(tx, ids...); err != nil {
+				return err
+			}
+		}
+
+		var inserts, updates []models.Product
+		var entries []models.ProductAuditEntry
+		// updated is the position of each product's row in updates. A product
+		// listed twice is written once with its last row, and each row is
+		// audited against the one before it.
+		updated := make(map[uint]int)
+		for i, product := range products {
+			before, ok := existing[product.ID]
+			switch {
+			case product.ID == 0:
+				product.Version = 1
+				inserts = append(inserts, product)
+			case !ok:
+				rejected[i] = ErrProductNotFound
+			case product.Stock < before.Stock && product.Stock < holds[product.ID]:
+				rejected[i] = ErrInsufficientStock
+			default:
+				row := models.Product{
+					ID:          product.ID,
+					Name:        product.Name,
+					Description: product.Description,
+					Price:       product.Price,
+					Stock:       product.Stock,
+					Version:     before.Version + 1,
+					CreatedAt:   before.CreatedAt,
+				}
+				if j, ok := updated[row.ID]; ok {
+					updates[j] = row
+				} else {
+					updated[row.ID] = len(updates)
+					updates = append(updates, row)
+				}
+				existing[row.ID] = row
+
+				after := row
+				entries = append(entries, newAuditEntry(models.AuditUpdate, &after, models.DiffProducts(&before, &after)))
+			}
+		}
+
+		if len(inserts) > 0 {
+			if err := tx.Create(&inserts).Error; err != nil {
+				return err
+			}
+			for i := range inserts {
+				entries = append(entries, newAuditEntry(models.AuditCreate, &inserts[i], models.DiffProducts(nil, &inserts[i])))
+			}
+		}
+
+		if len(updates) > 0 {
+			// The rows are locked, so the versions computed above are current
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "description", "price", "stock", "version", "updated_at"}),
+			}).Create(&updates).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, tx, entries...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+// Delete soft deletes a product. A non-zero version makes the delete
+// conditional on the stored version matching.
+func (r *productRepository) Delete(ctx context.Context, id uint, version uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := lockProduct(tx, id, &product); err != nil {
+			return err
+		}
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+
+		if err := tx.Delete(&product).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, newAuditEntry(models.AuditDelete, &product, nil))
+	})
+}
+
+// GetDeleted gets the soft deleted products matching the query with
+// pagination. Without a sort the most recently deleted come first.
+func (r *productRepository) GetDeleted(ctx context.Context, query models.ProductQuery) ([]models.Product, int64, error) {
+	var products []models.Product
+	var total int64
+
+	deleted := func() *gorm.DB {
+		return applyProductFilters(r.db.WithContext(ctx).Unscoped().Model(&models.Product{}).Where("deleted_at IS NOT NULL"), query)
+	}
+
+	if err := deleted().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	db := deleted()
+	if len(query.Sort) == 0 {
+		db = db.Order("deleted_at DESC").Order("id DESC")
+	} else {
+		db = applyProductSort(db, query.Sort)
+	}
+
+	offset := (query.Page - 1) * query.Limit
+	if err := db.Offset(offset).Limit(query.Limit).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Holds outlive a soft delete, so trashed products can still have some
+	if err := r.loadReserved(ctx, productPointers(products)...); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// Restore takes a soft deleted product out of the trash, incrementing its
+// version
+func (r *productRepository) Restore(ctx context.Context, id uint) (*models.Product, error) {
+	var product models.Product
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx.Unscoped(), id, &product); err != nil {
+			return err
+		}
+		if !product.DeletedAt.Valid {
+			return ErrProductNotDeleted
+		}
+
+		product.DeletedAt = gorm.DeletedAt{}
+		product.Version++
+		product.UpdatedAt = time.Now()
+		err := tx.Unscoped().Model(&product).UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    product.Version,
+			"updated_at": product.UpdatedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, newAuditEntry(models.AuditRestore, &product, nil))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadReserved(ctx, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// HardDelete permanently removes a product, whether or not it is in the
+// trash, together with its reservations. A non-zero version makes the delete
+// conditional on the stored version matching. The product's history is kept.
+func (r *productRepository) HardDelete(ctx context.Context, id uint, version uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := lockProduct(tx.Unscoped(), id, &product); err != nil {
+			return err
+		}
+		if version != 0 && product.Version != version {
+			return ErrVersionConflict
+		}
+
+		if err := tx.Unscoped().Delete(&product).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&models.Reservation{}).Error; err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, newAuditEntry(models.AuditPurge, &product, nil))
+	})
+}
+
+// purgeBatchSize bounds how many products PurgeDeleted locks and removes
+// in one transaction
+var purgeBatchSize = 500
+
+// PurgeDeleted permanently removes the products soft deleted before the
+// given time together with their reservations, returning how many products
+// were removed. Products are removed in batches, each in its own
+// transaction, so a failure keeps the batches already purged. The products'
+// history is kept.
+func (r *productRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		n, err := r.purgeBatch(ctx, before)
+		purged += n
+		if err != nil || n < int64(purgeBatchSize) {
+			return purged, err
+		}
+	}
+}
+
+// purgeBatch removes up to purgeBatchSize products soft deleted before the
+// given time, oldest IDs first
+func (r *productRepository) purgeBatch(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var products []models.Product
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at < ?", before).
+			Order("id").
+			Limit(purgeBatchSize).
+			Find(&products).Error
+		if err != nil || len(products) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(products))
+		entries := make([]models.ProductAuditEntry, len(products))
+		for i := range products {
+			ids[i] = products[i].ID
+			entries[i] = newAuditEntry(models.AuditPurge, &products[i], nil)
+		}
+
+		if err := tx.Where("product_id IN ?", ids).Delete(&models.Reservation{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.Product{}, ids)
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		return recordAudit(ctx, tx, entries...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// Truncate permanently removes every product, soft deleted ones included,
+// together with all reservations. A purge entry is recorded for every
+// product, so the audit trail shows who emptied the catalog.
+func (r *productRepository) Truncate(ctx context.Context) error {
+	return r.db.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Transaction(func(tx *gorm.DB) error {
+		var products []models.Product
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			FindInBatches(&products, purgeBatchSize, func(*gorm.DB, int) error {
+				entries := make([]models.ProductAuditEntry, len(products))
+				for i := range products {
+					entries[i] = newAuditEntry(models.AuditPurge, &products[i], nil)
+				}
+				return recordAudit(ctx, tx, entries...)
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&models.Reservation{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Product{}).Error
+	})
+}
+
+// GetHistory gets a product's audit entries with pagination, newest first.
+// The history of a purged product is still returned.
+func (r *productRepository) GetHistory(ctx context.Context, id uint, page, limit int) ([]models.ProductAuditEntry, int64, error) {
+	var entries []models.ProductAuditEntry
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&models.ProductAuditEntry{}).Where("product_id = ?", id)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total == 0 {
+		// Products from before the audit trail existed have no entries
+		if err := r.db.WithContext(ctx).Unscoped().First(&models.Product{}, id).Error; err != nil {
+			return nil, 0, translateError(err, ErrProductNotFound)
+		}
+		return entries, 0, nil
+	}
+
 	offset := (page - 1) * limit
-	err := r.db.Offset(offset).Limit(limit).Find(&products).Error
+	if err := db.Order("id DESC").Offset(offset).Limit(limit).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
 
-	return products, total, err
+	return entries, total, nil
 }
 
-// Update updates a product
-func (r *productRepository) Update(product *models.Product) error {
-	return r.db.Save(product).Error
+// lockProduct loads and locks a product for the rest of the transaction
+func lockProduct(tx *gorm.DB, id uint, product *models.Product) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(product, id).Error
+	return translateError(err, ErrProductNotFound)
 }
 
-// Delete soft deletes a product
-func (r *productRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Product{}, id).Error
+// Transaction runs fn with a repository bound to a single database
+// transaction, committing if fn returns nil and rolling back otherwise
+func (r *productRepository) Transaction(ctx context.Context, fn func(repo ProductRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&productRepository{db: tx})
+	})
 }
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"simple-goroutine-product/internal/apperrors"
+	"simple-goroutine-product/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReservationRepository interface for stock reservation data operations
+type ReservationRepository interface {
+	Create(ctx context.Context, reservation *models.Reservation) error
+	GetByID(ctx context.Context, id uint) (*models.Reservation, error)
+	Confirm(ctx context.Context, id uint) (*models.Reservation, error)
+	Release(ctx context.Context, id uint) (*models.Reservation, error)
+	ExpireStale(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ErrReservationNotFound is returned when a reservation does not exist
+var ErrReservationNotFound = apperrors.New(ErrNotFound, "reservation not found")
+
+// ErrReservationNotActive is returned when confirming or releasing a
+// reservation that is no longer holding stock
+var ErrReservationNotActive = apperrors.New(ErrConflict, "reservation is not active")
+
+// reservationRepository implements ReservationRepository
+type reservationRepository struct {
+	db *gorm.DB
+}
+
+// NewReservationRepository creates a new reservation repository
+func NewReservationRepository(db *gorm.DB) ReservationRepository {
+	return &reservationRepository{db: db}
+}
+
+// Create holds stock for a reservation if enough of it is available. The
+// product row is locked so concurrent holds cannot oversell.
+func (r *reservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, reservation.ProductID).Error
+		if err != nil {
+			return translateError(err, ErrProductNotFound)
+		}
+
+		reserved, err := activeHolds(tx, product.ID)
+		if err != nil {
+			return err
+		}
+
+		if product.Stock-reserved[product.ID] < reservation.Quantity {
+			return ErrInsufficientStock
+		}
+
+		reservation.Status = models.ReservationActive
+		return tx.Create(reservation).Error
+	})
+}
+
+// GetByID gets a reservation by ID
+func (r *reservationRepository) GetByID(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.WithContext(ctx).First(&reservation, id).Error
+	if err != nil {
+		return nil, translateError(err, ErrReservationNotFound)
+	}
+	return &reservation, nil
+}
+
+// Confirm turns an active reservation into a stock decrement, recorded in
+// the product's audit trail
+func (r *reservationRepository) Confirm(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockActive(tx, id, &reservation); err != nil {
+			return err
+		}
+
+		var product models.Product
+		result := tx.Model(&product).
+			Clauses(clause.Returning{}).
+			Where("id = ? AND stock >= ?", reservation.ProductID, reservation.Quantity).
+			UpdateColumns(map[string]interface{}{
+				"stock":      gorm.Expr("stock - ?", reservation.Quantity),
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+
+		before := product
+		before.Stock += reservation.Quantity
+		if err := recordAudit(ctx, tx, newAuditEntry(models.AuditUpdate, &product, models.DiffProducts(&before, &product))); err != nil {
+			return err
+		}
+
+		return tx.Model(&reservation).Update("status", models.ReservationConfirmed).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// Release gives the stock held by an active reservation back
+func (r *reservationRepository) Release(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockActive(tx, id, &reservation); err != nil {
+			return err
+		}
+		return tx.Model(&reservation).Update("status", models.ReservationReleased).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// ExpireStale marks every active reservation that expired before now
+func (r *reservationRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Reservation{}).
+		Where("status = ? AND expires_at <= ?", models.ReservationActive, now).
+		Update("status", models.ReservationExpired)
+	return result.RowsAffected, result.Error
+}
+
+// lockActive loads and locks a reservation, failing unless it is still
+// active. A hold past its expiry counts as inactive even before it is swept.
+func (r *reservationRepository) lockActive(tx *gorm.DB, id uint, reservation *models.Reservation) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(reservation, id).Error
+	if err != nil {
+		return translateError(err, ErrReservationNotFound)
+	}
+
+	if reservation.Status != models.ReservationActive {
+		return ErrReservationNotActive
+	}
+
+	if !reservation.ExpiresAt.After(time.Now()) {
+		return ErrReservationNotActive
+	}
+
+	return nil
+}
+
+// activeHolds sums the quantity of unexpired active reservations per product
+func activeHolds(db *gorm.DB, productIDs ...uint) (map[uint]int, error) {
+	var rows []struct {
+		ProductID uint
+		Quantity  int
+	}
+
+	err := db.Model(&models.Reservation{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Where("product_id IN ? AND status = ? AND expires_at > ?", productIDs, models.ReservationActive, time.Now()).
+		Group("product_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	holds := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		holds[row.ProductID] = row.Quantity
+	}
+	return holds, nil
+}
@@ -1,14 +1,16 @@
 package routes
 
 import (
+	"net/http"
 	"simple-goroutine-product/internal/handlers"
+	"simple-goroutine-product/internal/middlewares"
 
 	"github.com/labstack/echo/v4"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
 // SetupRoutes configures all routes for the application
-func SetupRoutes(e *echo.Echo, productHandler *handlers.ProductHandler) {
+func SetupRoutes(e *echo.Echo, productHandler *handlers.ProductHandler, reservationHandler *handlers.ReservationHandler, bulkHandler *handlers.BulkHandler, importHandler *handlers.ImportHandler, exportHandler *handlers.ExportHandler, healthHandler *handlers.HealthHandler, metricsHandler http.Handler, idempotency echo.MiddlewareFunc) {
 	// Swagger documentation
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
@@ -17,14 +19,32 @@ func SetupRoutes(e *echo.Echo, productHandler *handlers.ProductHandler) {
 
 	// Product routes
 	products := api.Group("/products")
-	products.POST("", productHandler.CreateProduct)
+	products.POST("", productHandler.CreateProduct, idempotency)
 	products.GET("", productHandler.GetProducts)
+	products.POST("/bulk", bulkHandler.ProcessBulk)
+	products.POST("/import", importHandler.ImportProducts, middlewares.Streaming())
+	products.GET("/export", exportHandler.ExportProducts, middlewares.Streaming())
+	products.GET("/trash", productHandler.GetDeletedProducts)
 	products.GET("/:id", productHandler.GetProduct)
 	products.PUT("/:id", productHandler.UpdateProduct)
+	products.PATCH("/:id", productHandler.PatchProduct)
 	products.DELETE("/:id", productHandler.DeleteProduct)
+	products.POST("/:id/restore", productHandler.RestoreProduct)
+	products.GET("/:id/history", productHandler.GetProductHistory)
+	products.POST("/:id/stock/adjust", productHandler.AdjustStock)
+	products.POST("/:id/reservations", reservationHandler.CreateReservation)
 
-	// Health check
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "ok"})
-	})
+	// Reservation routes
+	reservations := api.Group("/reservations")
+	reservations.GET("/:id", reservationHandler.GetReservation)
+	reservations.POST("/:id/confirm", reservationHandler.ConfirmReservation)
+	reservations.POST("/:id/release", reservationHandler.ReleaseReservation)
+
+	// Health checks
+	e.GET("/health", healthHandler.Health)
+	e.GET("/livez", healthHandler.Livez)
+	e.GET("/readyz", healthHandler.Readyz)
+
+	// Prometheus metrics
+	e.GET("/metrics", echo.WrapHandler(metricsHandler))
 }
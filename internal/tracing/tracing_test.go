@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"simple-goroutine-product/internal/config"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestSetup_Exporters(t *testing.T) {
+	for _, exporter := range []string{"none", "stdout", "otlp"} {
+		t.Run(exporter, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), config.TracingConfig{
+				Exporter:     exporter,
+				ServiceName:  "test",
+				OTLPEndpoint: "http://localhost:4318",
+				SampleRatio:  1,
+			})
+
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+func TestSetup_UnsupportedExporter(t *testing.T) {
+	_, err := Setup(context.Background(), config.TracingConfig{Exporter: "zipkin"})
+
+	assert.ErrorContains(t, err, `unsupported trace exporter "zipkin"`)
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGORMPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// Dry run builds statements and runs the callbacks without a server
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(&gormPlugin{tracer: provider.Tracer("test")}))
+
+	// Statements outside a trace are not recorded
+	db.Find(&[]widget{})
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	db.WithContext(ctx).Find(&[]widget{})
+	db.WithContext(ctx).Create(&widget{Name: "a"})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "gorm.query", spans[0].Name())
+	assert.Equal(t, "gorm.create", spans[1].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBCollectionName("widgets"))
+	assert.Contains(t, spans[0].Attributes(), semconv.DBSystemPostgreSQL)
+}
+
+func TestGORMPlugin_DBSystem(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(&gormPlugin{tracer: provider.Tracer("test")}))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	db.WithContext(ctx).Find(&[]widget{})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), semconv.DBSystemSqlite)
+	assert.Equal(t, semconv.DBSystemKey.String("cockroachdb"), dbSystem("cockroachdb"))
+}
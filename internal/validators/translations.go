@@ -0,0 +1,69 @@
+package validators
+
+import (
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// fallbackKey is the translation key of the message used for rules that
+// have no message of their own. {0} is the field and {1} the rule.
+const fallbackKey = "fallback"
+
+// registerEnglish adds the English validation messages
+func registerEnglish(v *validator.Validate, trans ut.Translator) error {
+	if err := en_translations.RegisterDefaultTranslations(v, trans); err != nil {
+		return err
+	}
+	return registerMessages(v, trans, "{0} failed the {1} rule", map[string]string{
+		"required_unless": "{0} is a required field",
+	})
+}
+
+// registerIndonesian adds the Indonesian validation messages
+func registerIndonesian(v *validator.Validate, trans ut.Translator) error {
+	if err := id_translations.RegisterDefaultTranslations(v, trans); err != nil {
+		return err
+	}
+	return registerMessages(v, trans, "{0} tidak memenuhi aturan {1}", map[string]string{
+		"required_unless": "{0} wajib diisi",
+	})
+}
+
+// registerMessages adds the fallback message and messages for rules the
+// upstream translations do not cover. {0} in a rule message is the field.
+func registerMessages(v *validator.Validate, trans ut.Translator, fallback string, rules map[string]string) error {
+	if err := trans.Add(fallbackKey, fallback, true); err != nil {
+		return err
+	}
+
+	for rule, message := range rules {
+		register := func(trans ut.Translator) error {
+			return trans.Add(rule, message, true)
+		}
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			message, _ := trans.T(fe.Tag(), fe.Field())
+			return message
+		}
+		if err := v.RegisterTranslation(rule, trans, register, translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translate returns the message for a failed rule, falling back to a
+// generic message when the locale has none for the rule
+func translate(fe validator.FieldError, trans ut.Translator) string {
+	message := fe.Translate(trans)
+	if message != fe.Error() {
+		return message
+	}
+
+	message, err := trans.T(fallbackKey, fe.Field(), fe.Tag())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
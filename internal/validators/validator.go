@@ -1,26 +1,184 @@
 package validators
 
 import (
+	"errors"
+	"reflect"
+	"simple-goroutine-product/internal/apperrors"
+	"simple-goroutine-product/internal/models"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
 )
 
 // CustomValidator implements echo.Validator interface
 type CustomValidator struct {
-	validator *validator.Validate
+	validator  *validator.Validate
+	translator *ut.UniversalTranslator
+
+	// mu guards the translators, the translations registered on validator
+	// and the locale matcher
+	mu      sync.RWMutex
+	locales []string
+	matcher language.Matcher
+}
+
+// ValidationError is returned when a struct fails validation. It lists every
+// failing field by its JSON name with messages in the default locale.
+type ValidationError struct {
+	Fields []models.FieldError
+
+	errs      validator.ValidationErrors
+	validator *CustomValidator
+}
+
+// Error joins the field messages into a single line
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap marks validation failures as domain validation errors
+func (e *ValidationError) Unwrap() error {
+	return apperrors.ErrValidation
+}
+
+// Localize returns the field errors with messages in the registered locale
+// that best matches an Accept-Language header, along with that locale
+func (e *ValidationError) Localize(acceptLanguage string) ([]models.FieldError, string) {
+	e.validator.mu.RLock()
+	defer e.validator.mu.RUnlock()
+
+	trans := e.validator.findTranslator(acceptLanguage)
+	return fieldErrors(e.errs, trans), trans.Locale()
 }
 
-// NewValidator creates a new custom validator
+// NewValidator creates a new custom validator with English and Indonesian
+// messages. English is the default locale.
 func NewValidator() *CustomValidator {
-	return &CustomValidator{
-		validator: validator.New(),
+	v := validator.New()
+
+	// Report fields by the name clients send them as
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	cv := &CustomValidator{
+		validator:  v,
+		translator: ut.New(en.New()),
+	}
+
+	// The built-in locales always register cleanly
+	if err := cv.RegisterLocale(en.New(), registerEnglish); err != nil {
+		panic(err)
+	}
+	if err := cv.RegisterLocale(id.New(), registerIndonesian); err != nil {
+		panic(err)
+	}
+
+	return cv
+}
+
+// RegisterLocale adds a locale that validation messages can be translated
+// into. register adds the locale's messages to the translator, usually by
+// calling one of the validator/v10/translations packages. The first
+// registered locale is the default. Locales may be registered while the
+// validator is in use.
+func (cv *CustomValidator) RegisterLocale(locale locales.Translator, register func(v *validator.Validate, trans ut.Translator) error) error {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if err := cv.translator.AddTranslator(locale, true); err != nil {
+		return err
+	}
+
+	trans, _ := cv.translator.GetTranslator(locale.Locale())
+	if err := register(cv.validator, trans); err != nil {
+		return err
 	}
+
+	cv.locales = append(cv.locales, locale.Locale())
+	tags := make([]language.Tag, len(cv.locales))
+	for i, name := range cv.locales {
+		tags[i] = language.Make(strings.ReplaceAll(name, "_", "-"))
+	}
+	cv.matcher = language.NewMatcher(tags)
+	return nil
 }
 
 // Validate validates a struct
 func (cv *CustomValidator) Validate(i interface{}) error {
-	if err := cv.validator.Struct(i); err != nil {
-		return echo.NewHTTPError(400, err.Error())
+	err := cv.validator.Struct(i)
+	if err == nil {
+		return nil
 	}
-	return nil
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+
+	return &ValidationError{
+		Fields:    fieldErrors(errs, cv.findTranslator("")),
+		errs:      errs,
+		validator: cv,
+	}
+}
+
+// findTranslator picks the registered locale that best matches an
+// Accept-Language header, falling back to the default locale. The caller
+// must hold cv.mu, which also guards the translations used by the returned
+// translator.
+func (cv *CustomValidator) findTranslator(acceptLanguage string) ut.Translator {
+	index := 0
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(tags) > 0 {
+		if _, i, confidence := cv.matcher.Match(tags...); confidence != language.No {
+			index = i
+		}
+	}
+
+	trans, _ := cv.translator.GetTranslator(cv.locales[index])
+	return trans
+}
+
+// fieldErrors converts validator errors into field errors translated with trans
+func fieldErrors(errs validator.ValidationErrors, trans ut.Translator) []models.FieldError {
+	fields := make([]models.FieldError, len(errs))
+	for i, fe := range errs {
+		fields[i] = models.FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: translate(fe, trans),
+		}
+	}
+	return fields
+}
+
+// fieldPath returns the dotted JSON path of a failing field without the
+// name of the struct being validated
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
 }